@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jkunzler0/chess/client/game"
+	"github.com/jkunzler0/chess/client/uci"
+)
+
+// Run './chess analyse -file <PGN> -game <N> -engine <path>' to have an engine
+// judge every move of a saved game
+func runAnalyse(args []string) error {
+	fs := flag.NewFlagSet("analyse", flag.ExitOnError)
+	file := fs.String("file", "", "PGN file to analyse\n")
+	n := fs.Int("game", 1, "Number of the game to analyse in a file of several games\n")
+	enginePath := fs.String("engine", "", "Path of a UCI engine to analyse with, the built-in engine if empty\n")
+	depth := fs.Int("depth", 0, "Deepest the engine searches each position, no limit if 0\n")
+	moveTime := fs.Duration("time", time.Second, "Longest the engine thinks about each position, no limit if 0\n")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("choose a PGN file with -file")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	games, err := game.ParsePGN(f)
+	if err != nil {
+		return err
+	}
+	if *n < 1 || *n > len(games) {
+		return fmt.Errorf("%s has %d games, cannot analyse game %d", *file, len(games), *n)
+	}
+
+	var analyser game.Analyser = game.NewEngine(16)
+	if *enginePath != "" {
+		engine, err := uci.Start(*enginePath)
+		if err != nil {
+			return err
+		}
+		defer engine.Close()
+		analyser = engine
+	}
+
+	g := games[*n-1]
+	fmt.Printf("%s vs %s, %s\n", g.Tag("White"), g.Tag("Black"), g.Result)
+	analyses, err := game.AnalyseGame(g, analyser, *depth, *moveTime)
+	for _, a := range analyses {
+		fmt.Println(a)
+	}
+	return err
+}
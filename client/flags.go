@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"time"
 
 	"github.com/jkunzler0/chess/client/p2p"
 	"github.com/thanhpk/randstr"
@@ -9,14 +10,38 @@ import (
 
 type config struct {
 	p2p       bool
+	ai        bool
+	aiDepth   int
+	aiTime    time.Duration
+	engine    string
+	book      string
+	color     string
 	nickname  string
+	fen       string
+	variant   string
+	pgnDir    string
+	tc        string
+	tui       bool
+	web       string
 	p2pConfig p2p.P2pConfig
 }
 
 func parseFlags() *config {
 	c := &config{}
 	flag.BoolVar(&c.p2p, "p2p", false, "P2P\n")
+	flag.BoolVar(&c.ai, "ai", false, "Play against the computer\n")
+	flag.IntVar(&c.aiDepth, "ai-depth", 0, "Deepest the computer searches, no limit if 0\n")
+	flag.DurationVar(&c.aiTime, "ai-time", time.Second, "Longest the computer thinks about a move, no limit if 0\n")
+	flag.StringVar(&c.engine, "engine", "", "Path of a UCI engine to play against with -ai, or for hints\n")
+	flag.StringVar(&c.book, "book", "", "Path of a Polyglot opening book, e.g. performance.bin, for book moves and the computer's openings, none if empty\n")
+	flag.StringVar(&c.color, "color", "white", "Your color against the computer, white or black\n")
 	flag.StringVar(&c.nickname, "nick", randstr.String(10), "Nickname\n")
+	flag.StringVar(&c.fen, "fen", "", "FEN of the starting position (White chooses in a P2P game)\n")
+	flag.StringVar(&c.variant, "variant", "", "Starting position: standard, chess960 for a random one, or chess960:N (White chooses in a P2P game)\n")
+	flag.StringVar(&c.tc, "tc", "", "Time control in minutes plus seconds, e.g. 5+3 for an increment or 5d3 for a delay, untimed if empty\n")
+	flag.BoolVar(&c.tui, "tui", false, "Play in a full-screen terminal interface, moving pieces with the arrow keys or mouse\n")
+	flag.StringVar(&c.web, "web", "", "Address to serve the game to a browser at, e.g. :8000 for this computer only or 0.0.0.0:8000 for any, none if empty\n")
+	flag.StringVar(&c.pgnDir, "pgn", "games", "Directory to save finished games in as PGN, or empty to not save them\n")
 	flag.StringVar(&c.p2pConfig.GroupID, "group", "01", "Group ID for finding specific games\n")
 	flag.StringVar(&c.p2pConfig.ListenHost, "host", "0.0.0.0", "Host listen address\n")
 	flag.StringVar(&c.p2pConfig.ProtocolID, "pid", "/chess/1.0.0", "Protocol ID for stream headers\n")
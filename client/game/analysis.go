@@ -0,0 +1,165 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// #######################################################################
+// (Section 1) Analysers #################################################
+// #######################################################################
+
+// Analyser is a chess engine that can be asked for the best move in a position,
+// either the built-in Engine or an external one such as a UCI executable
+type Analyser interface {
+	// Analyse searches the position reached by playing the moves from the FEN,
+	// to the given depth or for the given time, whichever comes first
+	// Moves are written in coordinate notation, e.g. "e7e8q"
+	Analyse(fen string, moves []string, depth int, moveTime time.Duration) (Analysis, error)
+}
+
+// Analysis is an analyser's verdict on a position
+type Analysis struct {
+	Move  string   // Best move in coordinate notation
+	Score int      // Centipawns for the side to move
+	Mate  int      // Moves until checkmate, negative if the side to move is mated, or 0
+	PV    []string // Best line found, starting with Move
+}
+
+var ErrorNoMoves = errors.New("no legal moves")
+
+// Analyse lets the built-in engine stand in for an external one
+func (e *Engine) Analyse(fen string, moves []string, depth int, moveTime time.Duration) (Analysis, error) {
+
+	p, err := ParseFEN(fen)
+	if err != nil {
+		return Analysis{}, err
+	}
+	seen := map[uint64]int{}
+	for _, s := range moves {
+		seen[p.hash()]++
+		if _, err = makeMove(p, s, p.whiteTurn); err != nil {
+			return Analysis{}, err
+		}
+	}
+
+	m, info, ok := e.Search(p, SearchParams{Depth: depth, MoveTime: moveTime, Seen: seen})
+	if !ok {
+		return Analysis{}, ErrorNoMoves
+	}
+	a := Analysis{Move: m.String(), Score: info.Score, Mate: info.Mate}
+	for _, pv := range info.PV {
+		a.PV = append(a.PV, pv.String())
+	}
+	return a, nil
+}
+
+// Return the moves played so far in coordinate notation
+func (gs *GameState) coordinateMoves() []string {
+	var moves []string
+	for _, u := range gs.history {
+		moves = append(moves, u.move.String())
+	}
+	return moves
+}
+
+// Check an analyser's move is legal in the position and return it in SAN
+func analysedSAN(p *Position, move string) (string, error) {
+	q := *p
+	m, err := makeMove(&q, move, q.whiteTurn)
+	if err != nil {
+		return "", fmt.Errorf("the engine played %s: %w", move, err)
+	}
+	return p.SAN(m), nil
+}
+
+// Format a score for the side to move from White's point of view, e.g. "+0.35" or "#-2"
+func formatScore(a Analysis, whiteTurn bool) string {
+	score, mate := a.Score, a.Mate
+	if !whiteTurn {
+		score, mate = -score, -mate
+	}
+	if mate != 0 {
+		return fmt.Sprintf("#%d", mate)
+	}
+	return fmt.Sprintf("%+.2f", float64(score)/100)
+}
+
+// #######################################################################
+// (Section 2) Hints #####################################################
+// #######################################################################
+
+// How long an analyser thinks about a hint
+const hintTime = time.Second
+
+// Print the move the analyser would play for the side to move
+func (gs *GameState) hint() {
+	if gs.analyser == nil {
+		// The built-in engine is only started once a hint is wanted
+		gs.analyser = NewEngine(16)
+	}
+	a, err := gs.analyser.Analyse(gs.start.FEN(), gs.coordinateMoves(), 0, hintTime)
+	if err != nil {
+		gs.out.Message(fmt.Sprint("No hint: ", err))
+		return
+	}
+	san, err := analysedSAN(gs.pos, a.Move)
+	if err != nil {
+		gs.out.Message(fmt.Sprint("No hint: ", err))
+		return
+	}
+	gs.out.Message(fmt.Sprintf("Hint: %s (%s)", san, formatScore(a, gs.pos.whiteTurn)))
+}
+
+// #######################################################################
+// (Section 3) Game Analysis #############################################
+// #######################################################################
+
+// MoveAnalysis compares a move played in a game with the analyser's choice
+type MoveAnalysis struct {
+	Number string   // e.g. "1." or "1..."
+	White  bool     // True if White played the move
+	SAN    string   // Move played
+	Best   string   // Analyser's choice in SAN
+	Before Analysis // Analysis of the position the move was played in
+}
+
+// String shows the move, the evaluation before it from White's point of view,
+// and the analyser's choice if it differs, e.g. "1. f3 +0.10 (best e4)"
+func (ma MoveAnalysis) String() string {
+	s := fmt.Sprintf("%s %s %s", ma.Number, ma.SAN, formatScore(ma.Before, ma.White))
+	if ma.Best != ma.SAN {
+		s += fmt.Sprintf(" (best %s)", ma.Best)
+	}
+	return s
+}
+
+// AnalyseGame asks the analyser about the position before every move of the game
+func AnalyseGame(g *PGNGame, an Analyser, depth int, moveTime time.Duration) ([]MoveAnalysis, error) {
+
+	var analyses []MoveAnalysis
+	p := g.Start
+	fen := p.FEN()
+	var played []string
+	for _, m := range g.Moves {
+		a, err := an.Analyse(fen, played, depth, moveTime)
+		if err != nil {
+			return analyses, err
+		}
+		best, err := analysedSAN(&p, a.Move)
+		if err != nil {
+			return analyses, err
+		}
+
+		number := fmt.Sprintf("%d.", p.fullmove)
+		if !p.whiteTurn {
+			number += ".."
+		}
+		analyses = append(analyses, MoveAnalysis{Number: number, White: p.whiteTurn, SAN: p.SAN(m), Best: best, Before: a})
+
+		p.Play(m)
+		played = append(played, m.String())
+	}
+	return analyses, nil
+}
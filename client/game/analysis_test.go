@@ -0,0 +1,112 @@
+package game
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// An analyser that always gives the same answer, as an external engine might
+type fixedAnalyser struct {
+	a     Analysis
+	calls int
+}
+
+func (f *fixedAnalyser) Analyse(fen string, moves []string, depth int, moveTime time.Duration) (Analysis, error) {
+	f.calls++
+	return f.a, nil
+}
+
+func TestEngineAnalyse(t *testing.T) {
+
+	e := NewEngine(1)
+	a, err := e.Analyse(StartFEN, []string{"f2f3", "e7e5", "g2g4"}, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Move != "d8h4" || a.Mate != 1 || a.PV[0] != "d8h4" {
+		t.Errorf("expected mate with d8h4, got %+v", a)
+	}
+
+	if _, err = e.Analyse(StartFEN, []string{"e2e5"}, 1, 0); err == nil {
+		t.Error("expected an illegal move to fail")
+	}
+	if _, err = e.Analyse(StartFEN, []string{"f2f3", "e7e5", "g2g4", "d8h4"}, 1, 0); err != ErrorNoMoves {
+		t.Error("expected no moves, got ", err)
+	}
+}
+
+func TestAnalyseGame(t *testing.T) {
+
+	games, err := ParsePGN(strings.NewReader("1. f3 e5 2. g4 Qh4# 0-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyses, err := AnalyseGame(games[0], NewEngine(1), 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analyses) != 4 {
+		t.Fatal("expected 4 moves, got ", len(analyses))
+	}
+	if s := analyses[3].String(); s != "2... Qh4# #-1" {
+		t.Error("got ", s)
+	}
+	if s := analyses[2].String(); !strings.HasPrefix(s, "2. g4 ") || !strings.Contains(s, "(best ") {
+		t.Error("expected g4 to differ from the best move, got ", s)
+	}
+
+	// An illegal move from the analyser stops the analysis
+	if _, err = AnalyseGame(games[0], &fixedAnalyser{a: Analysis{Move: "e2e5"}}, 1, 0); err == nil {
+		t.Error("expected an illegal move to fail")
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	for _, x := range []struct {
+		a     Analysis
+		white bool
+		want  string
+	}{
+		{Analysis{Score: 35}, true, "+0.35"},
+		{Analysis{Score: 35}, false, "-0.35"},
+		{Analysis{Score: -120}, false, "+1.20"},
+		{Analysis{Mate: 2}, false, "#-2"},
+	} {
+		if s := formatScore(x.a, x.white); s != x.want {
+			t.Errorf("expected %s, got %s", x.want, s)
+		}
+	}
+}
+
+func TestExternalOpponent(t *testing.T) {
+
+	// An external engine plays its moves through the game
+	f := &fixedAnalyser{a: Analysis{Move: "e7e5"}}
+	g, err := InitAI(AIParams{YouStart: true, MoveTime: time.Second, Engine: f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("e4\nhint\nq\n"), os.Stdout)
+	if o := g.PlayAI(); o != (Outcome{BlackWins, Resignation}) {
+		t.Error("expected white to resign, got ", o)
+	}
+	if sans := g.SANMoves(); len(sans) != 2 || sans[1] != "e5" {
+		t.Error("got moves ", sans)
+	}
+	if f.calls != 2 {
+		t.Error("expected the hint to ask the engine, got calls ", f.calls)
+	}
+
+	// One that plays an illegal move forfeits, here by playing e2e4 twice
+	f = &fixedAnalyser{a: Analysis{Move: "e2e4"}}
+	g, err = InitAI(AIParams{YouStart: false, MoveTime: time.Second, Engine: f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("e5\n"), os.Stdout)
+	if o := g.PlayAI(); o != (Outcome{BlackWins, Forfeit}) {
+		t.Error("expected the engine to forfeit, got ", o)
+	}
+}
@@ -0,0 +1,473 @@
+package game
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// #######################################################################
+// (Section 1) Bitboards #################################################
+// #######################################################################
+
+// A bitboard has one bit per square, numbered y*8 + x like the board,
+// so bit 0 is a8 and bit 63 is h1
+
+// Indexes of the pieces in bitboards.pieces, in the order of zobristPieces
+const (
+	wPawn = iota
+	wKnight
+	wBishop
+	wRook
+	wQueen
+	wKing
+	bPawn
+	bKnight
+	bBishop
+	bRook
+	bQueen
+	bKing
+)
+
+type bitboards struct {
+	pieces [12]uint64
+	colors [2]uint64 // Every White piece, then every Black piece
+}
+
+// Position with bitboards in place of the rune grid, for fast move generation
+type bitPosition struct {
+	bitboards
+	whiteTurn bool
+	castling  uint8 // Bit i is Position.castling[i]
+	enPassant int   // Square index, or -1
+	castle    *castleRules
+}
+
+func newBitboards(b board) bitboards {
+	var bb bitboards
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if i := pieceIndex(b[x][y]); i >= 0 {
+				bb.pieces[i] |= 1 << (y*8 + x)
+				bb.colors[i/6] |= 1 << (y*8 + x)
+			}
+		}
+	}
+	return bb
+}
+
+func newBitPosition(p *Position) *bitPosition {
+	bp := &bitPosition{bitboards: newBitboards(p.brd), whiteTurn: p.whiteTurn, enPassant: -1, castle: &standardCastleRules}
+	for i, ok := range p.castling {
+		if ok {
+			bp.castling |= 1 << i
+		}
+	}
+	if p.chess960 {
+		bp.castle = newCastleRules(p)
+	}
+	if p.enPassant != noSquare {
+		bp.enPassant = p.enPassant[1]*8 + p.enPassant[0]
+	}
+	return bp
+}
+
+// Return the index of the piece in bitboards.pieces, or -1 for an empty square
+func pieceIndex(piece rune) int {
+	for i, p := range zobristPieces {
+		if p == piece {
+			return i
+		}
+	}
+	return -1
+}
+
+// #######################################################################
+// (Section 2) Attack Tables #############################################
+// #######################################################################
+
+var (
+	knightAttacks [64]uint64
+	kingAttacks   [64]uint64
+	pawnAttacks   [2][64]uint64 // Squares attacked by a White, then Black, pawn
+	rays          [8][64]uint64 // Every square in each direction of rayDirections
+)
+
+// Sliding directions; the first four move to higher square indexes
+var rayDirections = [8][2]int{{1, 0}, {0, 1}, {1, 1}, {-1, 1}, {-1, 0}, {0, -1}, {-1, -1}, {1, -1}}
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		x, y := sq%8, sq/8
+		knightAttacks[sq] = jumps(x, y, [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}})
+		kingAttacks[sq] = jumps(x, y, rayDirections[:])
+		pawnAttacks[0][sq] = jumps(x, y, [][2]int{{1, -1}, {-1, -1}})
+		pawnAttacks[1][sq] = jumps(x, y, [][2]int{{1, 1}, {-1, 1}})
+		for d, i := range rayDirections {
+			for cx, cy := x+i[0], y+i[1]; inBounds(cx, cy); cx, cy = cx+i[0], cy+i[1] {
+				rays[d][sq] |= 1 << (cy*8 + cx)
+			}
+		}
+	}
+}
+
+// Return the squares reached by jumping from x, y in each direction
+func jumps(x int, y int, directions [][2]int) uint64 {
+	var bb uint64
+	for _, i := range directions {
+		if inBounds(x+i[0], y+i[1]) {
+			bb |= 1 << ((y+i[1])*8 + x + i[0])
+		}
+	}
+	return bb
+}
+
+// Return the squares of rank y from the lowest to the highest of the files
+func rankSpan(y int, files ...int) uint64 {
+	lo, hi := files[0], files[0]
+	for _, x := range files {
+		if x < lo {
+			lo = x
+		} else if x > hi {
+			hi = x
+		}
+	}
+	var bb uint64
+	for x := lo; x <= hi; x++ {
+		bb |= 1 << (y*8 + x)
+	}
+	return bb
+}
+
+// Return the squares a slider attacks in the given directions,
+// up to and including the first piece in the way
+func slide(sq int, occupied uint64, directions []int) uint64 {
+	var attacks uint64
+	for _, d := range directions {
+		ray := rays[d][sq]
+		if blockers := ray & occupied; blockers != 0 {
+			// Cut the ray off behind the nearest blocker
+			var first int
+			if d < 4 {
+				first = bits.TrailingZeros64(blockers)
+			} else {
+				first = 63 - bits.LeadingZeros64(blockers)
+			}
+			ray ^= rays[d][first]
+		}
+		attacks |= ray
+	}
+	return attacks
+}
+
+var (
+	rookDirections   = []int{0, 1, 4, 5}
+	bishopDirections = []int{2, 3, 6, 7}
+)
+
+func rookAttacks(sq int, occupied uint64) uint64 {
+	return slide(sq, occupied, rookDirections)
+}
+
+func bishopAttacks(sq int, occupied uint64) uint64 {
+	return slide(sq, occupied, bishopDirections)
+}
+
+// Return true if any piece of the given color attacks the square
+func (bb *bitboards) attacked(sq int, white bool) bool {
+	us, them := 0, 1
+	if !white {
+		us, them = 1, 0
+	}
+	p := bb.pieces[us*6 : us*6+6]
+	occupied := bb.colors[0] | bb.colors[1]
+
+	// A pawn attacks the square if a pawn of the other color on the square would attack it
+	return pawnAttacks[them][sq]&p[wPawn] != 0 ||
+		knightAttacks[sq]&p[wKnight] != 0 ||
+		kingAttacks[sq]&p[wKing] != 0 ||
+		bishopAttacks(sq, occupied)&(p[wBishop]|p[wQueen]) != 0 ||
+		rookAttacks(sq, occupied)&(p[wRook]|p[wQueen]) != 0
+}
+
+// Return true if the king of the given color is attacked
+// A side without a king is never in check
+func (bb *bitboards) kingAttacked(white bool) bool {
+	king := bb.pieces[wKing]
+	if !white {
+		king = bb.pieces[bKing]
+	}
+	if king == 0 {
+		return false
+	}
+	return bb.attacked(bits.TrailingZeros64(king), !white)
+}
+
+// Return whether the White and Black kings are in check, once both are on the board
+func (bb *bitboards) inCheck() ([2]bool, error) {
+	if bb.pieces[wKing] == 0 || bb.pieces[bKing] == 0 {
+		return [2]bool{false, false}, fmt.Errorf("board is missing kings")
+	}
+	return [2]bool{bb.kingAttacked(White), bb.kingAttacked(!White)}, nil
+}
+
+// #######################################################################
+// (Section 3) Move Generation ###########################################
+// #######################################################################
+
+type bitMove struct {
+	from, to  int
+	piece     int // Index into bitboards.pieces
+	capture   int // Index of the captured piece, or -1
+	promotion int // Index of the promoted piece, or -1
+	flags     MoveFlags
+}
+
+// castleRules hold the squares each castling right moves the king and rook between,
+// which stay the same for a whole game
+type castleRules struct {
+	king, rook     [4]int    // Start squares, indexed like Position.castling
+	kingTo, rookTo [4]int    // End squares
+	empty          [4]uint64 // Squares that must be empty, apart from the king and rook
+	kingPath       [4]uint64 // Squares the king cannot cross while they are attacked
+	masks          [64]uint8 // Castling rights lost when a piece moves from or to each square
+	takesRook      bool      // True in Chess960, where the king moves onto the rook to castle
+}
+
+var standardCastleRules = *newCastleRules(&Position{castleFiles: standardCastleFiles, brd: board{4: {'k', 7: 'K'}}})
+
+// Work out the castling squares of the position from its rooks and kings
+func newCastleRules(p *Position) *castleRules {
+	r := &castleRules{takesRook: p.chess960}
+	for i, rx := range p.castleFiles {
+		white := i < castleBK
+		y := homeRank(white)
+		kx := p.brd.kingFile(white)
+		if kx < 0 {
+			// Without a king on its home rank there are no castling rights to lose
+			continue
+		}
+		kx2, rx2 := castleDestinations(i)
+		r.king[i], r.rook[i] = y*8+kx, y*8+rx
+		r.kingTo[i], r.rookTo[i] = y*8+kx2, y*8+rx2
+		r.empty[i] = rankSpan(y, kx, rx, kx2, rx2) &^ (1<<r.king[i] | 1<<r.rook[i])
+		r.kingPath[i] = rankSpan(y, kx, kx2)
+		r.masks[r.king[i]] |= 1 << i
+		r.masks[r.rook[i]] |= 1 << i
+	}
+	return r
+}
+
+// Return every legal move for the side to move, appended to moves
+func (bp *bitPosition) legalMoves(moves []bitMove) []bitMove {
+	start := len(moves)
+	moves = bp.pseudoLegalMoves(moves)
+
+	// Keep only the moves that do not leave our king attacked
+	legal := moves[:start]
+	for _, m := range moves[start:] {
+		next := *bp
+		next.play(m)
+		if !next.kingAttacked(bp.whiteTurn) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// Return every move for the side to move, including those that leave the king in check
+func (bp *bitPosition) pseudoLegalMoves(moves []bitMove) []bitMove {
+
+	us, them := 0, 1
+	if !bp.whiteTurn {
+		us, them = 1, 0
+	}
+	base := us * 6
+	own, enemy := bp.colors[us], bp.colors[them]
+	occupied := own | enemy
+
+	// Add a move to each target square, looking up any captured piece
+	add := func(from int, targets uint64, piece int) {
+		for ; targets != 0; targets &= targets - 1 {
+			to := bits.TrailingZeros64(targets)
+			moves = append(moves, bitMove{from, to, piece, bp.pieceAt(to, them), -1, 0})
+		}
+	}
+
+	// Pawns
+	forward, startRank, lastRank := -8, 6, 0
+	if !bp.whiteTurn {
+		forward, startRank, lastRank = 8, 1, 7
+	}
+	for pawns := bp.pieces[base+wPawn]; pawns != 0; pawns &= pawns - 1 {
+		from := bits.TrailingZeros64(pawns)
+		targets := pawnAttacks[us][from] & enemy
+		if one := from + forward; one >= 0 && one < 64 && occupied&(1<<one) == 0 {
+			targets |= 1 << one
+			if two := one + forward; from/8 == startRank && occupied&(1<<two) == 0 {
+				moves = append(moves, bitMove{from, two, base + wPawn, -1, -1, DoublePush})
+			}
+		}
+		for ; targets != 0; targets &= targets - 1 {
+			to := bits.TrailingZeros64(targets)
+			capture := bp.pieceAt(to, them)
+			if to/8 != lastRank {
+				moves = append(moves, bitMove{from, to, base + wPawn, capture, -1, 0})
+				continue
+			}
+			for _, promotion := range []int{wQueen, wRook, wBishop, wKnight} {
+				moves = append(moves, bitMove{from, to, base + wPawn, capture, base + promotion, 0})
+			}
+		}
+		if bp.enPassant >= 0 && pawnAttacks[us][from]&(1<<bp.enPassant) != 0 {
+			moves = append(moves, bitMove{from, bp.enPassant, base + wPawn, them*6 + wPawn, -1, EnPassant})
+		}
+	}
+
+	// Knights, bishops, rooks, queens, and kings
+	for knights := bp.pieces[base+wKnight]; knights != 0; knights &= knights - 1 {
+		from := bits.TrailingZeros64(knights)
+		add(from, knightAttacks[from]&^own, base+wKnight)
+	}
+	for bishops := bp.pieces[base+wBishop]; bishops != 0; bishops &= bishops - 1 {
+		from := bits.TrailingZeros64(bishops)
+		add(from, bishopAttacks(from, occupied)&^own, base+wBishop)
+	}
+	for rooks := bp.pieces[base+wRook]; rooks != 0; rooks &= rooks - 1 {
+		from := bits.TrailingZeros64(rooks)
+		add(from, rookAttacks(from, occupied)&^own, base+wRook)
+	}
+	for queens := bp.pieces[base+wQueen]; queens != 0; queens &= queens - 1 {
+		from := bits.TrailingZeros64(queens)
+		add(from, (bishopAttacks(from, occupied)|rookAttacks(from, occupied))&^own, base+wQueen)
+	}
+	for kings := bp.pieces[base+wKing]; kings != 0; kings &= kings - 1 {
+		from := bits.TrailingZeros64(kings)
+		add(from, kingAttacks[from]&^own, base+wKing)
+	}
+
+	// Castling
+	for i := us * 2; i < us*2+2; i++ {
+		if bp.castling&(1<<i) == 0 {
+			continue
+		}
+		c := bp.castle
+		king, rook := c.king[i], c.rook[i]
+		if bp.pieces[base+wKing]&(1<<king) == 0 || bp.pieces[base+wRook]&(1<<rook) == 0 {
+			continue
+		}
+		// Every square the king or rook crosses must be empty
+		clear := occupied&c.empty[i] == 0
+		// The king cannot leave, cross, or land on an attacked square
+		for path := c.kingPath[i]; clear && path != 0; path &= path - 1 {
+			clear = !bp.attacked(bits.TrailingZeros64(path), !bp.whiteTurn)
+		}
+		if !clear {
+			continue
+		}
+		flag, to := KingsideCastle, c.kingTo[i]
+		if i == castleWQ || i == castleBQ {
+			flag = QueensideCastle
+		}
+		if c.takesRook {
+			to = rook
+		}
+		moves = append(moves, bitMove{king, to, base + wKing, -1, -1, flag})
+	}
+
+	return moves
+}
+
+// Return the index of the piece of the given color on the square, or -1
+func (bb *bitboards) pieceAt(sq int, color int) int {
+	if bb.colors[color]&(1<<sq) == 0 {
+		return -1
+	}
+	for i := color * 6; i < color*6+6; i++ {
+		if bb.pieces[i]&(1<<sq) != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Make a pseudo-legal move
+func (bp *bitPosition) play(m bitMove) {
+
+	us, them := 0, 1
+	if !bp.whiteTurn {
+		us, them = 1, 0
+	}
+	from, to := uint64(1)<<m.from, uint64(1)<<m.to
+
+	if m.capture >= 0 {
+		captured := to
+		if m.flags&EnPassant != 0 {
+			// The captured pawn is beside the start square, not on the end square
+			captured = 1 << (m.from/8*8 + m.to%8)
+		}
+		bp.pieces[m.capture] &^= captured
+		bp.colors[them] &^= captured
+	}
+
+	if m.flags&(KingsideCastle|QueensideCastle) != 0 {
+		// The king and rook land on the same squares as in standard chess, wherever they started
+		i := castleRight(bp.whiteTurn, m.flags&KingsideCastle != 0)
+		c := bp.castle
+		king := uint64(1)<<c.king[i] ^ uint64(1)<<c.kingTo[i]
+		rook := uint64(1)<<c.rook[i] ^ uint64(1)<<c.rookTo[i]
+		bp.pieces[m.piece] ^= king
+		bp.pieces[us*6+wRook] ^= rook
+		// The king and rook can land on each other's squares, so both are lifted before either is put down
+		bp.colors[us] = bp.colors[us]&^(uint64(1)<<c.king[i]|uint64(1)<<c.rook[i]) |
+			uint64(1)<<c.kingTo[i] | uint64(1)<<c.rookTo[i]
+	} else {
+		bp.pieces[m.piece] ^= from | to
+		bp.colors[us] ^= from | to
+		if m.promotion >= 0 {
+			bp.pieces[m.piece] &^= to
+			bp.pieces[m.promotion] |= to
+		}
+	}
+
+	bp.castling &^= bp.castle.masks[m.from] | bp.castle.masks[m.to]
+	bp.enPassant = -1
+	if m.flags&DoublePush != 0 {
+		bp.enPassant = (m.from + m.to) / 2
+	}
+	bp.whiteTurn = !bp.whiteTurn
+}
+
+// Convert a move into a Move
+func (m bitMove) toMove() Move {
+	mv := Move{
+		From:  Square{m.from % 8, m.from / 8},
+		To:    Square{m.to % 8, m.to / 8},
+		Piece: rune(zobristPieces[m.piece]),
+		Flags: m.flags,
+	}
+	if m.capture >= 0 {
+		mv.Capture = rune(zobristPieces[m.capture])
+	}
+	if m.promotion >= 0 {
+		mv.Promotion = rune(zobristPieces[m.promotion])
+	}
+	return mv
+}
+
+// Count the leaf nodes of the legal move tree to the given depth
+func (bp *bitPosition) perft(depth int) int {
+	if depth <= 0 {
+		return 1
+	}
+	moves := bp.legalMoves(make([]bitMove, 0, 64))
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		next := *bp
+		next.play(m)
+		nodes += next.perft(depth - 1)
+	}
+	return nodes
+}
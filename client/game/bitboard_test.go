@@ -0,0 +1,116 @@
+package game
+
+import (
+	"sort"
+	"testing"
+)
+
+// Count perft with the rune grid generator, to compare against the bitboards
+func gridPerft(p *Position, depth int) int {
+	if depth <= 0 {
+		return 1
+	}
+	moves := gridLegalMoves(p)
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		next := *p
+		next.Play(m)
+		nodes += gridPerft(&next, depth-1)
+	}
+	return nodes
+}
+
+func sortedMoves(moves []Move) []string {
+	var s []string
+	for _, m := range moves {
+		s = append(s, m.String())
+	}
+	sort.Strings(s)
+	return s
+}
+
+func TestBitboardsMatchGrid(t *testing.T) {
+
+	// Both generators must find exactly the same moves, with the same details,
+	// in every position two moves deep from each perft position
+	for _, x := range perftPositions {
+		p, _ := ParseFEN(x.fen)
+		positions := []*Position{p}
+		for _, m := range LegalMoves(p) {
+			next := *p
+			next.Play(m)
+			positions = append(positions, &next)
+		}
+
+		for _, p := range positions {
+			moves, gridMoves := LegalMoves(p), gridLegalMoves(p)
+			if len(moves) != len(gridMoves) {
+				t.Error(p.FEN(), " bitboards ", sortedMoves(moves), " grid ", sortedMoves(gridMoves))
+				continue
+			}
+			found := map[Move]bool{}
+			for _, m := range gridMoves {
+				found[m] = true
+			}
+			for _, m := range moves {
+				if !found[m] {
+					t.Error(p.FEN(), " grid is missing ", m, " ", m.Flags)
+				}
+			}
+		}
+	}
+}
+
+func TestSquareAttacked(t *testing.T) {
+	p, _ := ParseFEN("4k3/8/8/3p4/8/5n2/8/R3K2B w - - 0 1")
+	attacked := map[string][2]bool{ // By White, by Black
+		"a8": {true, false},
+		"d1": {true, false},
+		"f1": {true, false},
+		"e4": {false, true}, // Pawn, the bishop is blocked by the knight
+		"c4": {false, true}, // Pawn
+		"d4": {false, true}, // Knight
+		"g1": {false, true}, // Knight
+		"e2": {true, false}, // King
+		"e6": {false, false},
+		"g2": {true, false}, // Bishop
+		"f3": {true, false}, // Bishop, even though the square is occupied
+	}
+	for s, expected := range attacked {
+		x, y, _ := parseSquare(s)
+		if squareAttacked(p.brd, x, y, White) != expected[0] || squareAttacked(p.brd, x, y, !White) != expected[1] {
+			t.Error(s, " expected ", expected)
+		}
+	}
+}
+
+func BenchmarkPerftBitboards(b *testing.B) {
+	p, _ := ParseFEN(StartFEN)
+	for i := 0; i < b.N; i++ {
+		Perft(p, 3)
+	}
+}
+
+func BenchmarkPerftGrid(b *testing.B) {
+	p, _ := ParseFEN(StartFEN)
+	for i := 0; i < b.N; i++ {
+		gridPerft(p, 3)
+	}
+}
+
+func BenchmarkLegalMovesBitboards(b *testing.B) {
+	p, _ := ParseFEN(perftPositions[1].fen)
+	for i := 0; i < b.N; i++ {
+		LegalMoves(p)
+	}
+}
+
+func BenchmarkLegalMovesGrid(b *testing.B) {
+	p, _ := ParseFEN(perftPositions[1].fen)
+	for i := 0; i < b.N; i++ {
+		gridLegalMoves(p)
+	}
+}
@@ -25,8 +25,14 @@ func newBoard(pos string) (*board, error) {
 	for y, rank := range s {
 		x := 0
 		for _, piece := range rank {
+			if x >= 8 {
+				return &b, fmt.Errorf("board has too many pieces")
+			}
 			if strings.Contains("12345678", string(piece)) {
 				numOfSpaces := int(piece - '0')
+				if x+numOfSpaces > 8 {
+					return &b, fmt.Errorf("board has too many pieces")
+				}
 				for j := x; j < x+numOfSpaces; j++ {
 					b[j][y] = '-'
 				}
@@ -45,34 +51,82 @@ func newBoard(pos string) (*board, error) {
 	return &b, nil
 }
 
-func (b board) printBoardBasic() {
-	fmt.Println("   _A_B_C_D_E_F_G_H_")
+// Unicode symbols of the pieces
+var pieceSymbols = map[rune]string{'P': "\u2659", 'p': "\u265F",
+	'N': "\u2658", 'n': "\u265E",
+	'B': "\u2657", 'b': "\u265D",
+	'R': "\u2656", 'r': "\u265C",
+	'Q': "\u2655", 'q': "\u265B",
+	'K': "\u2654", 'k': "\u265A",
+	'-': "-"}
+
+// PieceSymbol returns the Unicode symbol of a piece, e.g. "\u2654" for 'K'
+func PieceSymbol(piece rune) string {
+	return pieceSymbols[piece]
+}
+
+// Return the board in letters, drawn from White's side or, if white is false, from Black's
+func (b board) basicLines(white bool) []string {
+	files := "ABCDEFGH"
+	if !white {
+		files = "HGFEDCBA"
+	}
+	lines := []string{"   _" + strings.Join(strings.Split(files, ""), "_") + "_"}
 	for i := 0; i < 8; i++ {
-		fmt.Print(8-i, " |")
+		y := orient(i, white)
+		line := fmt.Sprint(8-y, " |")
 		for j := 0; j < 8; j++ {
-			fmt.Print(" ", string(b[j][i]))
+			line += " " + string(b[orient(j, white)][y])
 		}
-		fmt.Println(" |")
+		lines = append(lines, line+" |")
 	}
-	fmt.Println("  |_________________|")
+	return append(lines, "  |_________________|")
 }
 
-func (b board) printBoard() {
-	var characters = map[rune]string{'P': "\u2659", 'p': "\u265F",
-		'N': "\u2658", 'n': "\u265E",
-		'B': "\u2657", 'b': "\u265D",
-		'R': "\u2656", 'r': "\u265C",
-		'Q': "\u2655", 'q': "\u265B",
-		'K': "\u2654", 'k': "\u265A",
-		'-': "-"}
+// Return the file or rank index drawn in the i-th column or row, counting from the top left
+func orient(i int, white bool) int {
+	if white {
+		return i
+	}
+	return 7 - i
+}
 
-	fmt.Println("   _A_B_C_D_E_F_G_H_")
-	for i := 0; i < 8; i++ {
-		fmt.Print(8-i, " |")
-		for j := 0; j < 8; j++ {
-			fmt.Print(" ", characters[b[j][i]])
+// Return the lines on the left with the lines on the right beside them
+func columns(left []string, right []string) []string {
+	var width int
+	for _, line := range left {
+		if n := VisibleLength(line); n > width {
+			width = n
+		}
+	}
+	var lines []string
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		lines = append(lines, strings.TrimRight(l+strings.Repeat(" ", width-VisibleLength(l))+"    "+r, " "))
+	}
+	return lines
+}
+
+// VisibleLength returns the number of characters in the line shown on a terminal, leaving out color codes
+func VisibleLength(line string) int {
+	n := 0
+	escape := false
+	for _, r := range line {
+		switch {
+		case r == '\x1b':
+			escape = true
+		case escape:
+			// Color codes end with m, e.g. "\x1b[0m"
+			escape = r != 'm'
+		default:
+			n++
 		}
-		fmt.Println(" |")
 	}
-	fmt.Println("  |_________________|")
+	return n
 }
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// #######################################################################
+// (Section 1) Chess960 Start Positions ##################################
+// #######################################################################
+
+// Chess960Count is the number of Chess960 start positions
+const Chess960Count = 960
+
+// The ten ways to place two knights on five empty squares, in Scharnagl's order
+var knightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960FEN returns the FEN of a Chess960 start position, numbered from 0 to 959
+// as by Scharnagl, so number 518 is the standard start position
+func Chess960FEN(n int) (string, error) {
+	if n < 0 || n >= Chess960Count {
+		return "", fmt.Errorf("invalid Chess960 position %d, expected 0 to %d", n, Chess960Count-1)
+	}
+
+	var rank [8]rune
+	// Place a piece on the given empty square, counting from the a-file
+	place := func(piece rune, empty int) {
+		for x := range rank {
+			if rank[x] != 0 {
+				continue
+			}
+			if empty == 0 {
+				rank[x] = piece
+				return
+			}
+			empty--
+		}
+	}
+
+	// The bishops go on opposite colors, then the queen, knights, and the king between the rooks
+	rank[2*(n%4)+1] = 'b'
+	n /= 4
+	rank[2*(n%4)] = 'b'
+	n /= 4
+	place('q', n%6)
+	n /= 6
+	knights := knightPlacements[n]
+	place('n', knights[1])
+	place('n', knights[0])
+	place('r', 0)
+	place('k', 0)
+	place('r', 0)
+
+	black := string(rank[:])
+	fen := fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w KQkq - 0 1", black, strings.ToUpper(black))
+	return fen, nil
+}
+
+// RandomChess960 returns the number of a Chess960 start position chosen at random
+func RandomChess960() int {
+	return rand.New(rand.NewSource(time.Now().UnixNano())).Intn(Chess960Count)
+}
+
+// ParseVariant returns the FEN of the start position of a variant, which is "standard",
+// "chess960" for a random Chess960 position, or "chess960:N" for position number N
+func ParseVariant(s string) (string, error) {
+	name, number, numbered := strings.Cut(s, ":")
+	switch {
+	case name == "standard" && !numbered:
+		return StartFEN, nil
+	case name == "chess960" && !numbered:
+		return Chess960FEN(RandomChess960())
+	case name == "chess960":
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return "", fmt.Errorf("invalid Chess960 position %q", number)
+		}
+		return Chess960FEN(n)
+	}
+	return "", fmt.Errorf("unknown variant %q, expected standard, chess960, or chess960:N", s)
+}
@@ -0,0 +1,210 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// Perft results for Chess960 positions, from the list published with Reinhard Scharnagl's
+// numbering at https://www.chessprogramming.org/Chess960_Perft_Results
+var chess960PerftPositions = []struct {
+	fen   string
+	nodes []int
+}{
+	{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189, 326672}},
+	{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002, 667366}},
+	{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{20, 479, 10471, 273318}},
+}
+
+func TestChess960FEN(t *testing.T) {
+
+	if fen, _ := Chess960FEN(518); fen != StartFEN {
+		t.Error("expected position 518 to be the standard start, got ", fen)
+	}
+	if fen, _ := Chess960FEN(0); !strings.HasPrefix(fen, "bbqnnrkr/") {
+		t.Error("got position 0 ", fen)
+	}
+
+	seen := map[string]bool{}
+	for n := 0; n < Chess960Count; n++ {
+		fen, err := Chess960FEN(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rank := strings.Split(fen, "/")[0]
+		if seen[rank] {
+			t.Fatal("duplicate position ", rank)
+		}
+		seen[rank] = true
+
+		// The bishops are on opposite colors and the king is between the rooks
+		b1, b2 := strings.IndexRune(rank, 'b'), strings.LastIndex(rank, "b")
+		r1, k, r2 := strings.IndexRune(rank, 'r'), strings.IndexRune(rank, 'k'), strings.LastIndex(rank, "r")
+		if (b1+b2)%2 == 0 || !(r1 < k && k < r2) {
+			t.Fatal("invalid position ", rank)
+		}
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// With the king and rooks on their standard squares, castling is standard too
+		standard := rank[0] == 'r' && rank[4] == 'k' && rank[7] == 'r'
+		if p.FEN() != fen || p.Chess960() == standard {
+			t.Fatal("expected the FEN back with Chess960 castling ", !standard, ", got ", p.FEN())
+		}
+	}
+
+	for _, s := range []string{"chess960:-1", "chess960:960", "chess960:x", "crazyhouse"} {
+		if _, err := ParseVariant(s); err == nil {
+			t.Error("expected an error for ", s)
+		}
+	}
+	if fen, _ := ParseVariant("chess960:518"); fen != StartFEN {
+		t.Error("got ", fen)
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	for _, x := range chess960PerftPositions {
+		p, err := ParseFEN(x.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Chess960() {
+			t.Error("expected Chess960 castling in ", x.fen)
+		}
+		for depth, want := range x.nodes {
+			if got := newBitPosition(p).perft(depth + 1); got != want {
+				t.Errorf("%s depth %d: expected %d, got %d", x.fen, depth+1, want, got)
+			}
+		}
+		// The rune grid agrees, though it is too slow to go as deep
+		if got := gridPerft(p, 2); got != x.nodes[1] {
+			t.Errorf("%s grid depth 2: expected %d, got %d", x.fen, x.nodes[1], got)
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+
+	// King on b1 with rooks on a1 and g1, so queenside the king moves right onto c1
+	fen := "1r2k1r1/1p4p1/8/8/8/8/8/RK4R1 w KQk - 0 1"
+	p, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.FEN(); got != fen {
+		t.Error("expected the FEN back, got ", got)
+	}
+
+	for move, want := range map[string]string{
+		"b1a1":  "1r2k1r1/1p4p1/8/8/8/8/8/2KR2R1 b k - 1 1",
+		"O-O-O": "1r2k1r1/1p4p1/8/8/8/8/8/2KR2R1 b k - 1 1",
+		"b1g1":  "1r2k1r1/1p4p1/8/8/8/8/8/R4RK1 b k - 1 1",
+		"O-O":   "1r2k1r1/1p4p1/8/8/8/8/8/R4RK1 b k - 1 1",
+	} {
+		q := *p
+		m, err := makeMove(&q, move, true)
+		if err != nil {
+			t.Error(move, err)
+			continue
+		}
+		if got := q.FEN(); got != want {
+			t.Errorf("%s: expected %s, got %s", move, want, got)
+		}
+		if m.Capture != 0 {
+			t.Error(move, " should not capture the rook")
+		}
+
+		// Taking the castle back puts the king and rook back where they started
+		u := newUndo(p)
+		u.move = m
+		unmakeMove(&q, u)
+		if q != *p {
+			t.Errorf("%s: expected %s after unmaking, got %s", move, fen, q.FEN())
+		}
+	}
+
+	// Moving the king two squares is not castling in Chess960
+	q := *p
+	if _, err = makeMove(&q, "b1d1", true); err == nil {
+		t.Error("expected b1d1 to be invalid")
+	}
+
+	// Black's king castles kingside without moving, and the SAN is still O-O
+	p, _ = ParseFEN("6kr/8/8/8/8/8/8/4K3 b h - 0 1")
+	moves := filterMoves(p, func(m Move) bool { return m.Flags&KingsideCastle != 0 })
+	if len(moves) != 1 || moves[0].String() != "g8h8" || p.SAN(moves[0]) != "O-O" {
+		t.Fatal("expected g8h8 to castle, got ", moves)
+	}
+	p.Play(moves[0])
+	if got := p.FEN(); got != "5rk1/8/8/8/8/8/8/4K3 w - - 1 2" {
+		t.Error("got ", got)
+	}
+}
+
+func TestChess960CastlingRights(t *testing.T) {
+
+	// K and Q name the outermost rooks, and file letters any other rook
+	for fen, want := range map[string]string{
+		"rk2r3/8/8/8/8/8/8/RK2R3 w KQkq - 0 1":   "rk2r3/8/8/8/8/8/8/RK2R3 w KQkq - 0 1",
+		"rk1rr3/8/8/8/8/8/8/RK1RR3 w DQd - 0 1":  "rk1rr3/8/8/8/8/8/8/RK1RR3 w DQd - 0 1",
+		"rk1rr3/8/8/8/8/8/8/RK1RR3 w EAea - 0 1": "rk1rr3/8/8/8/8/8/8/RK1RR3 w KQkq - 0 1",
+	} {
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Error(fen, err)
+			continue
+		}
+		if got := p.FEN(); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+
+	for _, fen := range []string{
+		"rk6/8/8/8/8/8/8/RK6 w K - 0 1",      // No rook on the kingside
+		"rk6/8/8/8/8/8/8/RK6 w B - 0 1",      // The king's own file
+		"rk6/8/8/8/8/8/8/RK6 w C - 0 1",      // No rook on the file
+		"rk6/8/8/8/8/8/K7/R7 w A - 0 1",      // No king on the home rank
+		"rk2r3/8/8/8/8/8/8/RK2R3 w EK - 0 1", // The same rook twice
+	} {
+		if _, err := ParseFEN(fen); err == nil {
+			t.Error("expected an error for ", fen)
+		}
+	}
+
+	// A standard setup written with file letters is standard chess
+	if p, _ := ParseFEN("r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1"); p.Chess960() || p.FEN() != "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1" {
+		t.Error("expected standard castling")
+	}
+}
+
+func TestChess960PGN(t *testing.T) {
+	fen, _ := Chess960FEN(200) // qbnrbknr
+	g, err := InitHotseat(HotseatParams{FEN: fen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []string{"g1f3", "g8f6", "g2g3", "g7g6", "O-O"} {
+		if err = g.play(m); err != nil {
+			t.Fatal(m, err)
+		}
+	}
+	pgn := g.PGN(Outcome{})
+	if !strings.Contains(pgn, `[Variant "Chess960"]`) || !strings.Contains(pgn, "3. O-O") {
+		t.Fatal("got ", pgn)
+	}
+
+	// The game reads back in
+	games, err := ParsePGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := games[0].Start
+	for _, m := range games[0].Moves {
+		p.Play(m)
+	}
+	if p != *g.pos {
+		t.Errorf("expected %s, got %s", g.FEN(), p.FEN())
+	}
+}
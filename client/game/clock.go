@@ -0,0 +1,266 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// #######################################################################
+// (Section 1) Time Controls #############################################
+// #######################################################################
+
+// TimeControl is the time each player starts with and the time they get for each move
+// A game has either a Fischer increment or a Bronstein delay, not both
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration // Fischer: added to the clock after every move
+	Delay     time.Duration // Bronstein: up to this much of the time used on a move is given back
+}
+
+// ParseTimeControl reads minutes plus seconds, e.g. "5+3" for a Fischer increment
+// or "5d3" for a Bronstein delay
+func ParseTimeControl(s string) (TimeControl, error) {
+
+	var tc TimeControl
+	base, extra, sep := s, "0", ""
+	if i := strings.IndexAny(s, "+d"); i >= 0 {
+		base, extra, sep = s[:i], s[i+1:], s[i:i+1]
+	}
+
+	minutes, err := strconv.ParseFloat(base, 64)
+	if err != nil || minutes <= 0 {
+		return tc, fmt.Errorf("invalid time control %q: the base must be a positive number of minutes", s)
+	}
+	seconds, err := strconv.ParseFloat(extra, 64)
+	if err != nil || seconds < 0 {
+		return tc, fmt.Errorf("invalid time control %q: the increment or delay must be a number of seconds", s)
+	}
+
+	tc.Base = time.Duration(minutes * float64(time.Minute))
+	if sep == "d" {
+		tc.Delay = time.Duration(seconds * float64(time.Second))
+	} else {
+		tc.Increment = time.Duration(seconds * float64(time.Second))
+	}
+	return tc, nil
+}
+
+// String returns the time control as a PGN TimeControl tag, e.g. "300+3"
+// PGN has no notation for a delay, so a delay is written like an increment
+func (tc TimeControl) String() string {
+	return fmt.Sprintf("%g+%g", tc.Base.Seconds(), (tc.Increment + tc.Delay).Seconds())
+}
+
+// #######################################################################
+// (Section 2) Clock #####################################################
+// #######################################################################
+
+// Clock keeps the time left for both players, and runs for the side to move
+type Clock struct {
+	tc        TimeControl
+	remaining [2]time.Duration // White's time, then Black's, as of the start of the current turn
+	white     bool             // True if White's time is running
+	since     time.Time        // When the current turn started
+	now       func() time.Time
+}
+
+func NewClock(tc TimeControl) *Clock {
+	return &Clock{tc: tc, remaining: [2]time.Duration{tc.Base, tc.Base}, now: time.Now}
+}
+
+// Index of a side in Clock.remaining
+func clockIndex(white bool) int {
+	if white {
+		return 0
+	}
+	return 1
+}
+
+// Start runs the clock of the given side from now
+func (c *Clock) Start(white bool) {
+	c.white = white
+	c.since = c.now()
+}
+
+// Elapsed returns the time used so far on the current turn
+func (c *Clock) Elapsed() time.Duration {
+	return c.now().Sub(c.since)
+}
+
+// Remaining returns the time a side has left, counting down for the side to move
+// A delay is used up before the clock starts counting down
+func (c *Clock) Remaining(white bool) time.Duration {
+	r := c.remaining[clockIndex(white)]
+	if white == c.white {
+		if used := c.Elapsed() - c.tc.Delay; used > 0 {
+			r -= used
+		}
+	}
+	if r < 0 {
+		return 0
+	}
+	return r
+}
+
+// Return what is left of the delay of the side to move
+func (c *Clock) delayLeft() time.Duration {
+	if d := c.tc.Delay - c.Elapsed(); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Allowed returns the time the side to move can use on their move
+func (c *Clock) Allowed() time.Duration {
+	return c.remaining[clockIndex(c.white)] + c.tc.Delay
+}
+
+// Deadline returns how long the side to move has until their flag falls
+func (c *Clock) Deadline() time.Duration {
+	return c.Allowed() - c.Elapsed()
+}
+
+// Flagged returns true if the side to move has run out of time
+func (c *Clock) Flagged() bool {
+	return c.Deadline() <= 0
+}
+
+// Press ends the turn of the side to move after they used the given time on their move,
+// and starts the other side's clock
+func (c *Clock) Press(used time.Duration) {
+	i := clockIndex(c.white)
+	delay := c.tc.Delay
+	if used < delay {
+		delay = used
+	}
+	c.remaining[i] += c.tc.Increment + delay - used
+	c.Start(!c.white)
+}
+
+// Switch starts the other side's clock, charging the time used to the side to move
+// without an increment or delay, as when a move is played again
+func (c *Clock) Switch() {
+	c.remaining[clockIndex(c.white)] -= c.Elapsed()
+	c.Start(!c.white)
+}
+
+// Reset sets the time both sides have left, e.g. back to before a move that is taken back,
+// and runs the clock of the given side from now
+func (c *Clock) Reset(remaining [2]time.Duration, white bool) {
+	c.remaining = remaining
+	c.Start(white)
+}
+
+// Resume runs the clock of the side to move again after it was paused, charging them
+// only the time they had used before the pause
+func (c *Clock) Resume(used time.Duration) {
+	c.since = c.now().Add(-used)
+}
+
+// String shows both clocks, e.g. "White 4:57  Black 5:00"
+func (c *Clock) String() string {
+	return fmt.Sprintf("White %s  Black %s", FormatClock(c.Remaining(true)), FormatClock(c.Remaining(false)))
+}
+
+// FormatClock shows the time left as minutes and seconds, with tenths of a second once it runs low
+func FormatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	if d < 10*time.Second {
+		return fmt.Sprintf("0:%04.1f", d.Seconds())
+	}
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// #######################################################################
+// (Section 3) Time Forfeits #############################################
+// #######################################################################
+
+var ErrorTimeout = errors.New("out of time")
+
+// Sent to the peer in place of a move by a player who ran out of time
+const flagMessage = "flag"
+
+// How much longer than their clock a P2P opponent is given for their move to arrive
+const latencyGrace = 2 * time.Second
+
+// The side to move loses on time, unless their opponent has too little left to checkmate with
+func (gs *GameState) timeout() Outcome {
+
+	// Only the opponent's pieces count, so take away the side to move's
+	b := gs.pos.brd
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if piece := b[x][y]; piece != 'K' && piece != 'k' && unicode.IsUpper(piece) == gs.pos.whiteTurn {
+				b[x][y] = '-'
+			}
+		}
+	}
+	if insufficientMaterial(b) {
+		return Outcome{Draw, TimeoutVsInsufficientMaterial}
+	}
+	return winFor(!gs.pos.whiteTurn, Timeout)
+}
+
+// Read your move before your flag falls, or return ErrorTimeout
+func (gs *GameState) readMove() (string, error) {
+	if gs.clock == nil {
+		return gs.in.NextMove(nil)
+	}
+
+	// Stop waiting for the move once the flag falls
+	stop := make(chan struct{})
+	timer := time.AfterFunc(gs.clock.Deadline(), func() { close(stop) })
+	defer timer.Stop()
+
+	move, err := gs.in.NextMove(stop)
+	if errors.Is(err, ErrorCanceled) || gs.clock.Flagged() {
+		return move, ErrorTimeout
+	}
+	return move, err
+}
+
+// Receive your opponent's move, or return ErrorTimeout once their flag has fallen
+// and their move has had time to arrive
+func (gs *GameState) receiveMove() (string, error) {
+	if gs.clock == nil {
+		return <-gs.rch, nil
+	}
+	select {
+	case move := <-gs.rch:
+		return move, nil
+	case <-time.After(gs.clock.Deadline() + latencyGrace):
+		return "", ErrorTimeout
+	}
+}
+
+// Pause the clock of the side to move while a takeback is asked for, returning a function
+// that runs it again from where it stopped if the takeback is declined
+func (gs *GameState) pauseClock() func() {
+	if gs.clock == nil {
+		return func() {}
+	}
+	used := gs.clock.Elapsed()
+	return func() { gs.clock.Resume(used) }
+}
+
+// Split the time used on a P2P move from the move, e.g. "e2e4@1500" is e2e4 after 1.5s
+// The time is measured by the player who moved, so network latency is not charged to them,
+// but they cannot claim to have used more time than it took their move to arrive
+func (gs *GameState) timedMove(message string) (string, time.Duration) {
+	used := gs.clock.Elapsed()
+	move, ms, ok := strings.Cut(message, "@")
+	if !ok {
+		return move, used
+	}
+	if n, err := strconv.ParseInt(ms, 10, 64); err == nil && n >= 0 && time.Duration(n)*time.Millisecond < used {
+		used = time.Duration(n) * time.Millisecond
+	}
+	return move, used
+}
@@ -0,0 +1,290 @@
+package game
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+
+	// Time control, then how it reads back and how PGN writes it
+	valid := map[string]TimeControl{
+		"5+3":     {Base: 5 * time.Minute, Increment: 3 * time.Second},
+		"5d3":     {Base: 5 * time.Minute, Delay: 3 * time.Second},
+		"10":      {Base: 10 * time.Minute},
+		"0.5+0.5": {Base: 30 * time.Second, Increment: 500 * time.Millisecond},
+	}
+	pgn := map[string]string{"5+3": "300+3", "5d3": "300+3", "10": "600+0", "0.5+0.5": "30+0.5"}
+	for x, expected := range valid {
+		tc, err := ParseTimeControl(x)
+		if err != nil {
+			t.Error(x, err)
+		} else if tc != expected {
+			t.Error(x, " expected ", expected, " got ", tc)
+		} else if tc.String() != pgn[x] {
+			t.Error(x, " expected ", pgn[x], " got ", tc)
+		}
+	}
+
+	// Series of invalid time controls
+	for _, s := range []string{"", "0+3", "-5+3", "5+", "5+x", "x+3", "5+-1", "5d3d"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Error(s, " expects error")
+		}
+	}
+}
+
+// A clock whose time only moves when the test says so
+func testClock(tc TimeControl) (*Clock, func(time.Duration)) {
+	now := time.Unix(0, 0)
+	c := NewClock(tc)
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestClockIncrement(t *testing.T) {
+
+	c, wait := testClock(TimeControl{Base: time.Minute, Increment: 2 * time.Second})
+	c.Start(true)
+	wait(10 * time.Second)
+	if r := c.Remaining(true); r != 50*time.Second {
+		t.Error("expected White's clock to run, got ", r)
+	}
+	if r := c.Remaining(false); r != time.Minute {
+		t.Error("expected Black's clock to be stopped, got ", r)
+	}
+
+	c.Press(c.Elapsed())
+	if r := c.Remaining(true); r != 52*time.Second {
+		t.Error("expected the increment to be added, got ", r)
+	}
+	wait(30 * time.Second)
+	if c.String() != "White 0:52  Black 0:30" {
+		t.Error("got ", c)
+	}
+
+	wait(30 * time.Second)
+	if !c.Flagged() || c.Remaining(false) != 0 {
+		t.Error("expected Black to flag, got ", c)
+	}
+}
+
+func TestClockDelay(t *testing.T) {
+
+	c, wait := testClock(TimeControl{Base: time.Minute, Delay: 5 * time.Second})
+	c.Start(true)
+
+	// No time is lost within the delay
+	wait(3 * time.Second)
+	if r := c.Remaining(true); r != time.Minute {
+		t.Error("expected the delay to be used first, got ", r)
+	}
+	c.Press(c.Elapsed())
+	if r := c.Remaining(true); r != time.Minute {
+		t.Error("expected no time to be lost, got ", r)
+	}
+
+	// And unlike an increment, time is never gained
+	wait(20 * time.Second)
+	c.Press(c.Elapsed())
+	if r := c.Remaining(false); r != 45*time.Second {
+		t.Error("expected 15s to be lost, got ", r)
+	}
+
+	// The flag falls once the delay and the time left are used up
+	wait(64 * time.Second)
+	if c.Flagged() {
+		t.Error("expected White to have one more second")
+	}
+	wait(2 * time.Second)
+	if !c.Flagged() {
+		t.Error("expected White to flag")
+	}
+}
+
+func TestClocksAfter(t *testing.T) {
+
+	p, _ := ParseFEN(StartFEN)
+	v := BoardView{Position: *p, Timed: true, Clocks: [2]time.Duration{time.Minute, time.Minute}, Delay: 3 * time.Second}
+
+	// Time White used since the view was given, then White's clock
+	clocks := map[time.Duration]time.Duration{
+		2 * time.Second: time.Minute, // Within the delay
+		5 * time.Second: 58 * time.Second,
+		2 * time.Minute: 0,
+	}
+	for d, expected := range clocks {
+		if c := v.ClocksAfter(d); c[0] != expected || c[1] != time.Minute {
+			t.Error(d, " expected ", expected, " got ", c)
+		}
+	}
+}
+
+func TestClockUndo(t *testing.T) {
+
+	g, err := InitHotseat(HotseatParams{TimeControl: TimeControl{Base: time.Minute, Increment: 2 * time.Second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, wait := testClock(g.clock.tc)
+	g.clock = c
+	c.Start(true)
+	for _, x := range []string{"e2e4", "e7e5"} {
+		wait(10 * time.Second)
+		if err := g.play(x); err != nil {
+			t.Fatal(x, err)
+		}
+		c.Press(c.Elapsed())
+	}
+
+	// Taking a move back gives back the time used on it, and on the turn since
+	wait(5 * time.Second)
+	g.undo()
+	if r := c.Remaining(false); r != time.Minute {
+		t.Error("expected Black's time to be given back, got ", r)
+	}
+	if r := c.Remaining(true); r != 52*time.Second {
+		t.Error("expected White's time to be unchanged, got ", r)
+	}
+	g.undo()
+	if c.String() != "White 1:00  Black 1:00" {
+		t.Error("expected the clocks to be as they started, got ", c)
+	}
+}
+
+func TestFormatClock(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Second:                         "0:00.0",
+		0:                                    "0:00.0",
+		9*time.Second + 450*time.Millisecond: "0:09.4",
+		10 * time.Second:                     "0:10",
+		5*time.Minute + 7*time.Second + 900*time.Millisecond: "5:07",
+		90 * time.Minute: "90:00",
+	}
+	for d, expected := range tests {
+		if s := FormatClock(d); s != expected {
+			t.Error(d, " expected ", expected, " got ", s)
+		}
+	}
+}
+
+func TestTimedMove(t *testing.T) {
+
+	g, err := InitHotseat(HotseatParams{TimeControl: TimeControl{Base: time.Minute}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, wait := testClock(g.clock.tc)
+	g.clock = c
+	c.Start(true)
+	wait(3 * time.Second)
+
+	// Message from the opponent, then the time the move is taken to have used
+	claims := map[string]time.Duration{
+		"e2e4@1500": 1500 * time.Millisecond,
+		"e2e4@5000": 3 * time.Second, // The time the move took to arrive is the most that can be claimed
+		"e2e4@-1":   3 * time.Second,
+		"e2e4":      3 * time.Second,
+	}
+	for x, expected := range claims {
+		move, used := g.timedMove(x)
+		if move != "e2e4" || used != expected {
+			t.Error(x, " expected e2e4 after ", expected, " got ", move, " after ", used)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+
+	outcomes := map[string]Outcome{
+		"":                                  {BlackWins, Timeout},
+		"4k3/8/8/8/8/8/8/4K2R w - - 0 1":    {Draw, TimeoutVsInsufficientMaterial}, // Black cannot checkmate with a lone king
+		"4k3/7p/8/8/8/8/8/4K3 w - - 0 1":    {BlackWins, Timeout},                  // But can with a pawn
+		"4k3/8/8/8/8/8/7P/b3K3 w - - 0 1":   {Draw, TimeoutVsInsufficientMaterial}, // Nor with a lone bishop
+		"4k3/8/8/8/8/8/7P/n3K3 w - - 0 1":   {Draw, TimeoutVsInsufficientMaterial}, // Nor a lone knight
+		"2b1k3/8/8/8/8/8/7P/b3K3 w - - 0 1": {BlackWins, Timeout},                  // But can with bishops on both colors
+	}
+	for fen, expected := range outcomes {
+		g, err := InitHotseat(HotseatParams{FEN: fen, TimeControl: TimeControl{Base: 50 * time.Millisecond}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// White never moves
+		r, w := io.Pipe()
+		defer w.Close()
+		g.in = NewTerminal(r, os.Stdout)
+		if o := g.PlayHotseat(); o != expected {
+			t.Error(fen, " expected ", expected, " got ", o)
+		}
+	}
+}
+
+func TestP2pClock(t *testing.T) {
+
+	rch, wch := make(chan string, 1), make(chan string, 1)
+	g, err := InitP2P(P2PParams{
+		YouStart:    false,
+		TimeControl: TimeControl{Base: time.Minute, Increment: time.Second},
+		ReadChan:    rch,
+		WriteChan:   wch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("e7e5\nq\n"), os.Stdout)
+
+	done := make(chan bool)
+	go func() {
+		// White claims to have taken no time at all
+		rch <- "e2e4@0"
+		// Your move is sent with the time you took
+		if move := <-wch; !strings.HasPrefix(move, "e7e5@") {
+			t.Error("expected a timed move, got ", move)
+		}
+		rch <- flagMessage
+		<-wch
+		close(done)
+	}()
+
+	if o := g.PlayP2P(); o != (Outcome{BlackWins, Timeout}) {
+		t.Error("expected white to lose on time, got ", o)
+	}
+	<-done
+	if r := g.clock.remaining[0]; r != time.Minute+time.Second {
+		t.Error("expected White's claimed time to be used, got ", r)
+	}
+}
+
+func TestP2pTakebackClock(t *testing.T) {
+
+	rch, wch := make(chan string, 1), make(chan string, 1)
+	g, err := InitP2P(P2PParams{
+		YouStart:    true,
+		TimeControl: TimeControl{Base: time.Minute},
+		ReadChan:    rch,
+		WriteChan:   wch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("e2e4\nundo\nq\n"), os.Stdout)
+
+	done := make(chan bool)
+	go func() {
+		<-wch // e2e4
+		rch <- "e7e5@0"
+		<-wch // The takeback request
+		// Your clock is stopped while your opponent thinks it over
+		time.Sleep(200 * time.Millisecond)
+		rch <- takebackDecline
+		<-wch // q
+		close(done)
+	}()
+
+	g.PlayP2P()
+	<-done
+	if r := g.clock.Remaining(true); r < time.Minute-100*time.Millisecond {
+		t.Error("expected White's clock to be stopped during the takeback request, got ", r)
+	}
+}
@@ -0,0 +1,585 @@
+package game
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// #######################################################################
+// (Section 1) Engine ####################################################
+// #######################################################################
+
+// Scores beyond mateScore - maxPly are checkmates, counted in plies from the root
+const (
+	mateScore = 100000
+	maxPly    = 128
+	infinity  = mateScore + 1
+)
+
+// SearchParams limit a search, which stops at whichever limit it reaches first
+type SearchParams struct {
+	Depth    int             // Deepest iteration, or 0 for no limit
+	MoveTime time.Duration   // Time to search for, or 0 for no limit
+	Nodes    int             // Nodes to search, or 0 for no limit
+	Stop     <-chan struct{} // Closed to stop the search early, or nil
+	Seen     map[uint64]int  // Positions already reached in the game by hash, drawn if reached again
+}
+
+// SearchInfo reports a completed iteration of a search
+type SearchInfo struct {
+	Depth int
+	Score int // Centipawns for the side to move
+	Mate  int // Moves until checkmate, negative if the side to move is mated, or 0
+	Nodes int
+	Time  time.Duration
+	PV    []Move // Best line found
+}
+
+// Engine searches for the best move with alpha-beta and iterative deepening
+type Engine struct {
+	Info func(SearchInfo) // Called after every completed iteration, may be nil
+
+	tt      []ttEntry
+	killers [maxPly][2]bitMove
+	nodes   int
+	path    []uint64 // Hashes of the positions from the root to the current node
+	params  SearchParams
+	depth   int // Depth of the current iteration
+	start   time.Time
+	stopped bool
+}
+
+// NewEngine creates an engine with a transposition table of about the given size
+func NewEngine(megabytes int) *Engine {
+	entries := 1
+	for entries*2*ttEntrySize <= megabytes<<20 {
+		entries *= 2
+	}
+	return &Engine{tt: make([]ttEntry, entries)}
+}
+
+// Clear forgets everything learned in earlier searches, as before a new game
+func (e *Engine) Clear() {
+	for i := range e.tt {
+		e.tt[i] = ttEntry{}
+	}
+	e.killers = [maxPly][2]bitMove{}
+}
+
+// Search returns the best legal move in the position, and the last completed iteration
+// It returns false if the side to move has no legal moves
+func (e *Engine) Search(p *Position, params SearchParams) (Move, SearchInfo, bool) {
+
+	root := newBitPosition(p)
+	moves := root.legalMoves(nil)
+	if len(moves) == 0 {
+		return Move{}, SearchInfo{}, false
+	}
+
+	e.params, e.start, e.nodes, e.stopped = params, time.Now(), 0, false
+	e.path = e.path[:0]
+	e.killers = [maxPly][2]bitMove{}
+
+	best := moves[0]
+	var info SearchInfo
+	for depth := 1; depth <= maxPly && (params.Depth == 0 || depth <= params.Depth); depth++ {
+		e.depth = depth
+		score := e.negamax(root, depth, 0, -infinity, infinity)
+		// An unfinished iteration is thrown away, and as the first is never stopped
+		// there is always a finished one to play from
+		if e.stopped {
+			break
+		}
+		pv := e.principalVariation(root, depth)
+		if len(pv) > 0 {
+			best = pv[0]
+		}
+		info = SearchInfo{Depth: depth, Score: score, Mate: mateIn(score), Nodes: e.nodes, Time: time.Since(e.start)}
+		for _, m := range pv {
+			info.PV = append(info.PV, m.toMove())
+		}
+		if e.Info != nil {
+			e.Info(info)
+		}
+		// There is no point searching deeper once a forced mate is found
+		if e.stopped || info.Mate != 0 && abs(score) >= mateScore-depth {
+			break
+		}
+	}
+	return best.toMove(), info, true
+}
+
+// Return the number of moves until checkmate for a mate score, or 0
+func mateIn(score int) int {
+	switch {
+	case score > mateScore-maxPly:
+		return (mateScore - score + 1) / 2
+	case score < -mateScore+maxPly:
+		return -(mateScore + score + 1) / 2
+	}
+	return 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Return true once the search has run out of time or nodes, or been told to stop
+// The time is checked every few thousand nodes, as looking at it is not free,
+// and nothing is checked before the first iteration is finished
+func (e *Engine) shouldStop() bool {
+	if e.stopped || e.depth == 1 {
+		return e.stopped
+	}
+	if e.params.Nodes > 0 && e.nodes >= e.params.Nodes {
+		e.stopped = true
+	}
+	if e.stopped || e.nodes&2047 != 0 {
+		return e.stopped
+	}
+	if e.params.MoveTime > 0 && time.Since(e.start) >= e.params.MoveTime {
+		e.stopped = true
+	}
+	select {
+	case <-e.params.Stop:
+		e.stopped = true
+	default:
+	}
+	return e.stopped
+}
+
+// #######################################################################
+// (Section 2) Search ####################################################
+// #######################################################################
+
+// Return the score of the position for the side to move, searching depth plies
+func (e *Engine) negamax(bp *bitPosition, depth int, ply int, alpha int, beta int) int {
+
+	e.nodes++
+	if e.shouldStop() {
+		return 0
+	}
+
+	h := bp.hash()
+	if ply > 0 && e.repeated(h) {
+		return 0
+	}
+	inCheck := bp.kingAttacked(bp.whiteTurn)
+	if inCheck && ply < 2*e.depth {
+		// Search on while in check, but only until the line is twice the depth
+		// of the iteration, so a long series of checks cannot run on to maxPly
+		depth++
+	}
+	if depth <= 0 || ply >= maxPly-1 {
+		return e.quiesce(bp, ply, alpha, beta)
+	}
+
+	// A deep enough result from earlier can be used as is
+	ttMove, hit := e.probe(h, depth, ply, alpha, beta)
+	if hit.ok && ply > 0 {
+		return hit.score
+	}
+
+	moves := bp.legalMoves(make([]bitMove, 0, 64))
+	if len(moves) == 0 {
+		if inCheck {
+			return -mateScore + ply
+		}
+		return 0
+	}
+	e.orderMoves(moves, ttMove, ply)
+
+	e.path = append(e.path, h)
+	defer func() { e.path = e.path[:len(e.path)-1] }()
+
+	origAlpha := alpha
+	best := -infinity
+	var bestMove bitMove
+	for _, m := range moves {
+		next := *bp
+		next.play(m)
+		score := -e.negamax(&next, depth-1, ply+1, -beta, -alpha)
+		if e.stopped {
+			return 0
+		}
+		if score > best {
+			best, bestMove = score, m
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			// A quiet move good enough to cut off the search is tried early at this ply elsewhere
+			if m.capture < 0 && m.promotion < 0 && e.killers[ply][0] != m {
+				e.killers[ply][1], e.killers[ply][0] = e.killers[ply][0], m
+			}
+			break
+		}
+	}
+
+	flag := ttExact
+	if best <= origAlpha {
+		flag = ttUpper
+	} else if best >= beta {
+		flag = ttLower
+	}
+	e.store(h, depth, ply, best, flag, bestMove)
+	return best
+}
+
+// Search only captures and promotions until the position is quiet,
+// so the evaluation never stops halfway through an exchange or while in check
+func (e *Engine) quiesce(bp *bitPosition, ply int, alpha int, beta int) int {
+
+	e.nodes++
+	if e.shouldStop() {
+		return 0
+	}
+
+	// In check there is no standing pat, and every way out of check is searched
+	inCheck := bp.kingAttacked(bp.whiteTurn)
+	if !inCheck {
+		// Standing pat: the side to move does not have to capture
+		standPat := bp.evaluate()
+		if standPat >= beta || ply >= maxPly-1 {
+			return standPat
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+	}
+
+	moves := bp.legalMoves(make([]bitMove, 0, 64))
+	if inCheck {
+		if len(moves) == 0 {
+			return -mateScore + ply
+		}
+		if ply >= maxPly-1 {
+			return bp.evaluate()
+		}
+	}
+	tactical := moves[:0]
+	for _, m := range moves {
+		if inCheck || m.capture >= 0 || m.promotion >= 0 {
+			tactical = append(tactical, m)
+		}
+	}
+	e.orderMoves(tactical, bitMove{}, ply)
+
+	for _, m := range tactical {
+		next := *bp
+		next.play(m)
+		score := -e.quiesce(&next, ply+1, -beta, -alpha)
+		if e.stopped {
+			return 0
+		}
+		if score >= beta {
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// Return true if the position was reached earlier in the search or the game
+func (e *Engine) repeated(h uint64) bool {
+	for _, seen := range e.path {
+		if seen == h {
+			return true
+		}
+	}
+	return e.params.Seen[h] > 0
+}
+
+// Sort the moves so the ones most likely to be best are searched first:
+// the move from the transposition table, then captures of the most valuable piece
+// by the least valuable one, then promotions, then killer moves
+func (e *Engine) orderMoves(moves []bitMove, ttMove bitMove, ply int) {
+	score := func(m bitMove) int {
+		switch {
+		case m == ttMove && (m.from != 0 || m.to != 0):
+			return 1 << 20
+		case m.capture >= 0:
+			return 1<<16 + pieceValues[m.capture%6]*16 - pieceValues[m.piece%6]/16
+		case m.promotion >= 0:
+			return 1<<15 + pieceValues[m.promotion%6]
+		case m == e.killers[ply][0]:
+			return 1 << 14
+		case m == e.killers[ply][1]:
+			return 1<<14 - 1
+		}
+		return 0
+	}
+	keys := make([]int, len(moves))
+	for i, m := range moves {
+		keys[i] = score(m)
+	}
+	sort.Stable(byKey{moves, keys})
+}
+
+// Sorts moves by their keys, highest first
+type byKey struct {
+	moves []bitMove
+	keys  []int
+}
+
+func (b byKey) Len() int           { return len(b.moves) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] > b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.moves[i], b.moves[j] = b.moves[j], b.moves[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// Follow the best moves stored in the transposition table
+func (e *Engine) principalVariation(bp *bitPosition, depth int) []bitMove {
+	var pv []bitMove
+	seen := map[uint64]bool{}
+	p := *bp
+	for len(pv) < depth {
+		h := p.hash()
+		entry := &e.tt[h&uint64(len(e.tt)-1)]
+		if entry.key != h || seen[h] || !p.isLegal(entry.move) {
+			break
+		}
+		seen[h] = true
+		pv = append(pv, entry.move)
+		p.play(entry.move)
+	}
+	return pv
+}
+
+// Return true if the move is one of the legal moves in the position
+func (bp *bitPosition) isLegal(m bitMove) bool {
+	for _, legal := range bp.legalMoves(make([]bitMove, 0, 64)) {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+// #######################################################################
+// (Section 3) Transposition Table #######################################
+// #######################################################################
+
+type ttFlag uint8
+
+const (
+	ttExact ttFlag = iota + 1
+	ttLower        // The score is at least this
+	ttUpper        // The score is at most this
+)
+
+type ttEntry struct {
+	key   uint64
+	move  bitMove
+	score int32
+	depth int16
+	flag  ttFlag
+}
+
+// Approximate size of a ttEntry in bytes
+const ttEntrySize = 48
+
+// The result of looking up a position in the transposition table
+type ttHit struct {
+	score int
+	ok    bool // True if the score can be used without searching
+}
+
+// Look up the position, returning its best move and a score if the entry is deep enough
+func (e *Engine) probe(h uint64, depth int, ply int, alpha int, beta int) (bitMove, ttHit) {
+	entry := &e.tt[h&uint64(len(e.tt)-1)]
+	if entry.key != h {
+		return bitMove{}, ttHit{}
+	}
+	if int(entry.depth) < depth {
+		return entry.move, ttHit{}
+	}
+	score := fromTT(int(entry.score), ply)
+	switch {
+	case entry.flag == ttExact,
+		entry.flag == ttLower && score >= beta,
+		entry.flag == ttUpper && score <= alpha:
+		return entry.move, ttHit{score, true}
+	}
+	return entry.move, ttHit{}
+}
+
+// Store a result, replacing whatever was in its slot
+func (e *Engine) store(h uint64, depth int, ply int, score int, flag ttFlag, m bitMove) {
+	e.tt[h&uint64(len(e.tt)-1)] = ttEntry{h, m, int32(toTT(score, ply)), int16(depth), flag}
+}
+
+// Mate scores are stored counted from the position rather than the root,
+// so they stay right when the position is reached at another ply
+func toTT(score int, ply int) int {
+	switch {
+	case score > mateScore-maxPly:
+		return score + ply
+	case score < -mateScore+maxPly:
+		return score - ply
+	}
+	return score
+}
+
+func fromTT(score int, ply int) int {
+	switch {
+	case score > mateScore-maxPly:
+		return score - ply
+	case score < -mateScore+maxPly:
+		return score + ply
+	}
+	return score
+}
+
+// #######################################################################
+// (Section 4) Hashing ###################################################
+// #######################################################################
+
+// Return the Zobrist hash of the position, the same as Position.hash
+func (bp *bitPosition) hash() uint64 {
+	var h uint64
+	for piece := 0; piece < 12; piece++ {
+		for b := bp.pieces[piece]; b != 0; b &= b - 1 {
+			sq := bits.TrailingZeros64(b)
+			h ^= zobrist.pieces[piece][sq%8][sq/8]
+		}
+	}
+	if !bp.whiteTurn {
+		h ^= zobrist.black
+	}
+	for i := range zobrist.castling {
+		if bp.castling&(1<<i) != 0 {
+			h ^= zobrist.castling[i]
+		}
+	}
+	// Like Position.hash, the en passant square only counts if a pawn can capture on it
+	if bp.enPassant >= 0 {
+		us := 0
+		if !bp.whiteTurn {
+			us = 1
+		}
+		if pawnAttacks[1-us][bp.enPassant]&bp.pieces[us*6+wPawn] != 0 {
+			h ^= zobrist.enPassant[bp.enPassant%8]
+		}
+	}
+	return h
+}
+
+// #######################################################################
+// (Section 5) Playing the Computer ######################################
+// #######################################################################
+
+type AIParams struct {
+	YouStart    bool          // True if you play White
+	FEN         string        // Starting position, the standard one if empty
+	Tags        PGNTags       // Headers of the game's PGN
+	PGNDir      string        // Directory to save the finished game in, or empty to not save it
+	TimeControl TimeControl   // Untimed if the base is zero
+	Depth       int           // Deepest the computer searches, or 0 for no limit
+	MoveTime    time.Duration // Longest the computer thinks about a move, or 0 for no limit
+	Engine      Analyser      // Plays in place of the built-in engine if not nil, e.g. a UCI executable
+	Book        *Book         // Opening book the computer plays from while it can, or nil
+	Input       MoveSource    // Where your moves come from, the terminal if nil
+	Output      Renderer      // Where the game is shown, the terminal if nil
+}
+
+func InitAI(p AIParams) (*GameState, error) {
+	if p.Depth == 0 && p.MoveTime == 0 {
+		return nil, fmt.Errorf("the computer needs a depth or a time per move")
+	}
+	gs, err := InitHotseat(HotseatParams{FEN: p.FEN, Tags: p.Tags, PGNDir: p.PGNDir, TimeControl: p.TimeControl,
+		Book: p.Book, Input: p.Input, Output: p.Output})
+	if err != nil {
+		return nil, err
+	}
+	gs.white = p.YouStart
+	gs.opponent = p.Engine
+	if gs.opponent == nil {
+		gs.opponent = NewEngine(16)
+	}
+	// Hints come from the computer opponent too
+	gs.analyser = gs.opponent
+	gs.search = SearchParams{Depth: p.Depth, MoveTime: p.MoveTime}
+	return gs, nil
+}
+
+// Let the computer choose and make its move
+func (gs *GameState) computerTurn() Outcome {
+
+	// The computer plays from its book for as long as the game is in it
+	if m, ok := gs.bookMove(); ok {
+		gs.out.Message("Computer plays " + gs.pos.SAN(m) + " from the book")
+		return gs.theirTurn(m.String())
+	}
+
+	moveTime := gs.search.MoveTime
+	if gs.clock != nil {
+		// Never use more than a small share of the time left
+		budget := gs.clock.Deadline()/20 + gs.clock.tc.Increment
+		if moveTime == 0 || budget < moveTime {
+			moveTime = budget
+		}
+	}
+
+	// An external engine that fails or plays an illegal move forfeits the game
+	a, err := gs.opponent.Analyse(gs.start.FEN(), gs.coordinateMoves(), gs.search.Depth, moveTime)
+	if err != nil {
+		gs.out.Message(fmt.Sprint("The computer failed to move: ", err))
+		return winFor(gs.white, Forfeit)
+	}
+	san, err := analysedSAN(gs.pos, a.Move)
+	if err != nil {
+		gs.out.Message(fmt.Sprint("The computer failed to move: ", err))
+		return winFor(gs.white, Forfeit)
+	}
+	gs.out.Message("Computer plays " + san)
+	return gs.theirTurn(a.Move)
+}
+
+func (gs *GameState) PlayAI() Outcome {
+
+	gs.out.Message("----- Chess Against the Computer -----")
+	gs.out.Message("For a hotseat or p2p game or game instructions, see `./chess -help`.")
+	if gs.clock != nil {
+		gs.clock.Start(gs.pos.whiteTurn)
+	}
+	gs.printBoard()
+
+	// A game started from a FEN could already be over
+	outcome, err := gs.outcome()
+	if err != nil {
+		panic(err)
+	}
+
+	for !outcome.Over() {
+		if gs.pos.whiteTurn == gs.white {
+			gs.out.Message("Your Turn")
+			outcome, _ = gs.yourTurn()
+		} else {
+			gs.out.Message("Computer's Turn")
+			outcome = gs.computerTurn()
+		}
+	}
+
+	gs.out.GameOver(outcome)
+
+	if outcome.Result == Draw {
+		gs.out.Message("~~~Draw~~~")
+	} else if outcome.Won(gs.white) {
+		gs.out.Message("~~~You Win!~~~")
+	} else {
+		gs.out.Message("~~~You Lose~~~")
+	}
+	gs.saveFinishedGame(outcome)
+
+	return outcome
+}
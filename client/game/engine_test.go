@@ -0,0 +1,228 @@
+package game
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBitPositionHash(t *testing.T) {
+
+	// Hashing the bitboards agrees with hashing the rune grid, two moves deep
+	for _, x := range perftPositions {
+		p, err := ParseFEN(x.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range LegalMoves(p) {
+			q := *p
+			q.Play(m)
+			for _, m2 := range LegalMoves(&q) {
+				r := q
+				r.Play(m2)
+				if newBitPosition(&r).hash() != r.hash() {
+					t.Fatal("hashes differ in ", r.FEN())
+				}
+			}
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+
+	p, _ := ParseFEN(StartFEN)
+	if score := newBitPosition(p).evaluate(); score != 0 {
+		t.Error("expected the start position to be even, got ", score)
+	}
+
+	// Mirrored positions have the same score for the side to move
+	for _, fens := range [][2]string{
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1"},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", "4k3/4p3/8/8/8/8/8/4K3 b - - 0 1"},
+	} {
+		p1, _ := ParseFEN(fens[0])
+		p2, _ := ParseFEN(fens[1])
+		if s1, s2 := newBitPosition(p1).evaluate(), newBitPosition(p2).evaluate(); s1 != s2 {
+			t.Error(fens, " expected the same score, got ", s1, " and ", s2)
+		}
+	}
+
+	// An extra queen is worth a lot
+	p, _ = ParseFEN("4k3/8/8/8/8/8/8/3QK3 b - - 0 1")
+	if score := newBitPosition(p).evaluate(); score > -800 {
+		t.Error("expected Black to be losing, got ", score)
+	}
+}
+
+func TestSearch(t *testing.T) {
+
+	// Position, then the moves that are best in it
+	best := map[string][]string{
+		"7k/8/6K1/8/8/8/8/R7 w - - 0 1":                                       {"a1a8"},                 // Back rank mate
+		"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4": {"h5f7"},                 // Scholar's mate
+		"k7/8/2K5/8/8/8/8/7R w - - 0 1":                                       {"c6b6", "c6c7", "h1h7"}, // Mate in two
+		"7k/8/8/8/8/r7/1r6/7K w - - 0 1":                                      {"h1g1"},                 // Mated in one
+		"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1":                                   {"d2d5"},                 // Free queen
+		"8/4P1k1/8/8/8/8/8/4K3 w - - 0 1":                                     {"e7e8q"},                // Promotion
+	}
+	// The depth to search each position to, and the mate to find there in moves
+	limits := map[string][2]int{
+		"7k/8/6K1/8/8/8/8/R7 w - - 0 1":                                       {2, 1},
+		"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4": {2, 1},
+		"k7/8/2K5/8/8/8/8/7R w - - 0 1":                                       {4, 2},
+		"7k/8/8/8/8/r7/1r6/7K w - - 0 1":                                      {3, -1},
+		"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1":                                   {2, 0},
+		"8/4P1k1/8/8/8/8/8/4K3 w - - 0 1":                                     {3, 0},
+	}
+	e := NewEngine(1)
+	for fen, expected := range best {
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Clear()
+		m, info, ok := e.Search(p, SearchParams{Depth: limits[fen][0]})
+		if !ok {
+			t.Error(fen, " expected a move")
+			continue
+		}
+		if !strings.Contains(strings.Join(expected, " "), m.String()) {
+			t.Error(fen, " expected one of ", expected, " got ", m)
+		}
+		if info.Mate != limits[fen][1] {
+			t.Error(fen, " expected mate ", limits[fen][1], " got ", info.Mate)
+		}
+		if len(info.PV) == 0 || info.PV[0] != m {
+			t.Error(fen, " expected the PV to start with ", m, " got ", info.PV)
+		}
+	}
+
+	// Quiescence sees the queen would be recaptured
+	p, _ := ParseFEN("4k3/8/2p5/3p4/8/8/8/3QK3 w - - 0 1")
+	if m, _, ok := e.Search(p, SearchParams{Depth: 1}); !ok || m.String() == "d1d5" {
+		t.Error("expected the queen not to take the defended pawn, got ", m)
+	}
+
+	// No legal moves
+	p, _ = ParseFEN("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+	if _, _, ok := e.Search(p, SearchParams{Depth: 1}); ok {
+		t.Error("expected no move in stalemate")
+	}
+}
+
+func TestQuiesce(t *testing.T) {
+
+	// In check, the side to move cannot stand pat on their material
+	e := NewEngine(1)
+	mated := map[string]int{
+		"R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1":  -mateScore, // Black is mated a rook down
+		"6k1/5ppp/8/8/8/8/5PPP/rq4K1 w - - 0 1": -mateScore, // White is mated a queen and rook down
+	}
+	for fen, expected := range mated {
+		p, _ := ParseFEN(fen)
+		if score := e.quiesce(newBitPosition(p), 0, -infinity, infinity); score != expected {
+			t.Error(fen, " expected ", expected, " got ", score)
+		}
+	}
+
+	// And every way out of check is searched, not only captures
+	p, _ := ParseFEN("6k1/5ppp/8/8/8/8/5PP1/r5K1 w - - 0 1")
+	if score := e.quiesce(newBitPosition(p), 0, -infinity, infinity); score <= -mateScore+maxPly {
+		t.Error("expected White to escape check, got ", score)
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+
+	e := NewEngine(1)
+	p, _ := ParseFEN(perftPositions[1].fen)
+
+	start := time.Now()
+	var depths []int
+	e.Info = func(info SearchInfo) { depths = append(depths, info.Depth) }
+	if _, _, ok := e.Search(p, SearchParams{MoveTime: 100 * time.Millisecond}); !ok {
+		t.Fatal("expected a move")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("expected the search to stop after 100ms, took ", elapsed)
+	}
+	for i, d := range depths {
+		if d != i+1 {
+			t.Fatal("expected every depth in order, got ", depths)
+		}
+	}
+
+	// A search with no limits runs until it is stopped
+	stop := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+	start = time.Now()
+	e.Info = nil
+	if _, _, ok := e.Search(p, SearchParams{Stop: stop}); !ok {
+		t.Fatal("expected a move")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("expected the search to stop after 50ms, took ", elapsed)
+	}
+
+	// The first iteration finishes even when the search is stopped from the start
+	_, full, _ := NewEngine(1).Search(p, SearchParams{Depth: 1})
+	var first SearchInfo
+	e = NewEngine(1)
+	e.Info = func(info SearchInfo) {
+		if info.Depth == 1 {
+			first = info
+		}
+	}
+	closed := make(chan struct{})
+	close(closed)
+	if _, _, ok := e.Search(p, SearchParams{Stop: closed}); !ok || first.Nodes != full.Nodes {
+		t.Error("expected depth 1 to be searched in full, got ", first.Nodes, " nodes of ", full.Nodes)
+	}
+}
+
+func TestSearchRepetition(t *testing.T) {
+
+	// White is lost, except that repeating a position already seen in the game is a draw
+	p, _ := ParseFEN("7k/8/8/8/8/8/q7/7K w - - 0 1")
+	q := *p
+	q.Play(Move{From: Square{7, 7}, To: Square{6, 7}, Piece: 'K'})
+	seen := map[uint64]int{q.hash(): 1}
+
+	e := NewEngine(1)
+	_, without, _ := e.Search(p, SearchParams{Depth: 3})
+	e.Clear()
+	_, with, _ := e.Search(p, SearchParams{Depth: 3, Seen: seen})
+	if without.Score >= 0 || with.Score < without.Score {
+		t.Error("expected the repetition to help White, got ", without.Score, " without and ", with.Score, " with")
+	}
+}
+
+func TestPlayAI(t *testing.T) {
+
+	// The computer finds the mate
+	g, err := InitAI(AIParams{FEN: "7k/8/6K1/8/8/8/8/R7 w - - 0 1", Depth: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := g.PlayAI(); o != (Outcome{WhiteWins, Checkmate}) {
+		t.Error("expected the computer to mate, got ", o)
+	}
+
+	// Taking back a move also takes back the computer's reply
+	g, err = InitAI(AIParams{YouStart: true, Depth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("undo\ne4\nundo\nd4\nq\n"), os.Stdout)
+	if o := g.PlayAI(); o != (Outcome{BlackWins, Resignation}) {
+		t.Error("expected white to resign, got ", o)
+	}
+	if sans := g.SANMoves(); len(sans) != 2 || sans[0] != "d4" {
+		t.Error("got moves ", sans)
+	}
+
+	if _, err = InitAI(AIParams{}); err == nil {
+		t.Error("expected an error without a depth or time")
+	}
+}
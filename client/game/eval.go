@@ -0,0 +1,129 @@
+package game
+
+import (
+	"math/bits"
+)
+
+// #######################################################################
+// (Section 1) Evaluation ################################################
+// #######################################################################
+
+// Values of the pieces in centipawns, indexed like bitboards.pieces for White
+var pieceValues = [6]int{100, 320, 330, 500, 900, 0}
+
+// Piece-square tables in centipawns, from the Simplified Evaluation Function at
+// https://www.chessprogramming.org/Simplified_Evaluation_Function
+// Each table is for White and indexed like a bitboard, so a8 comes first
+var pieceSquareTables = [6][64]int{
+	wPawn: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	wKnight: {
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	},
+	wBishop: {
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	},
+	wRook: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	},
+	wQueen: {
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	},
+	// The king hides behind its pawns until the endgame
+	wKing: {
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	},
+}
+
+// In the endgame the king comes out to the center
+var kingEndgameTable = [64]int{
+	-50, -40, -30, -20, -20, -30, -40, -50,
+	-30, -20, -10, 0, 0, -10, -20, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -30, 0, 0, 0, 0, -30, -30,
+	-50, -30, -30, -30, -30, -30, -30, -50,
+}
+
+// Return the score of the position in centipawns for the side to move
+func (bp *bitPosition) evaluate() int {
+
+	// The endgame starts once the queens are gone, or each side has at most a queen and a minor piece
+	endgame := true
+	for color := 0; color < 2; color++ {
+		base := color * 6
+		queens := bits.OnesCount64(bp.pieces[base+wQueen])
+		minors := bits.OnesCount64(bp.pieces[base+wKnight] | bp.pieces[base+wBishop])
+		rooks := bits.OnesCount64(bp.pieces[base+wRook])
+		if queens > 0 && (rooks > 0 || minors > 1) {
+			endgame = false
+		}
+	}
+
+	score := 0
+	for piece := 0; piece < 12; piece++ {
+		kind, sign, mirror := piece%6, 1, 0
+		if piece >= 6 {
+			// Black's squares are White's flipped top to bottom
+			sign, mirror = -1, 56
+		}
+		table := &pieceSquareTables[kind]
+		if kind == wKing && endgame {
+			table = &kingEndgameTable
+		}
+		for b := bp.pieces[piece]; b != 0; b &= b - 1 {
+			sq := bits.TrailingZeros64(b) ^ mirror
+			score += sign * (pieceValues[kind] + table[sq])
+		}
+	}
+
+	if !bp.whiteTurn {
+		return -score
+	}
+	return score
+}
@@ -0,0 +1,373 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// #######################################################################
+// (Section 1) Position ##################################################
+// #######################################################################
+
+// StartFEN is the FEN of the standard starting position
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Indexes into Position.castling
+const (
+	castleWK = iota // White kingside (K)
+	castleWQ        // White queenside (Q)
+	castleBK        // Black kingside (k)
+	castleBQ        // Black queenside (q)
+)
+
+// noSquare marks an empty en passant target
+var noSquare = [2]int{-1, -1}
+
+// Position is everything needed to continue a game from a FEN
+type Position struct {
+	brd         board
+	whiteTurn   bool
+	castling    [4]bool
+	castleFiles [4]int // File of the rook for each castling right, the corners in standard chess
+	chess960    bool   // True if the kings and rooks castle from Chess960 start squares
+	enPassant   [2]int // Square a pawn skipped over on the last move, or noSquare
+	halfmove    int    // Halfmoves since the last capture or pawn move
+	fullmove    int    // Starts at 1 and is incremented after Black moves
+}
+
+// WhiteTurn returns true if it is White's turn to move
+func (p *Position) WhiteTurn() bool {
+	return p.whiteTurn
+}
+
+// Chess960 returns true if castling follows the Chess960 rules, which is when the king
+// or a rook with castling rights did not start on its standard square
+// Castling moves are then written as the king taking its own rook, e.g. "b1a1"
+func (p *Position) Chess960() bool {
+	return p.chess960
+}
+
+// SetChess960 makes castling follow the Chess960 rules, e.g. in a Chess960 game
+// whose start position happens to be the standard one
+func (p *Position) SetChess960() {
+	p.chess960 = true
+}
+
+// Piece returns the piece on a square, e.g. 'N' for a White knight, or 0 if it is empty
+func (p *Position) Piece(s Square) rune {
+	if piece := p.brd[s[0]][s[1]]; piece != '-' {
+		return piece
+	}
+	return 0
+}
+
+// #######################################################################
+// (Section 2) Parsing ###################################################
+// #######################################################################
+
+// Names of the FEN fields, as reported by FENError
+const (
+	FieldCount     = "field count"
+	FieldPlacement = "piece placement"
+	FieldSide      = "side to move"
+	FieldCastling  = "castling"
+	FieldEnPassant = "en passant"
+	FieldHalfmove  = "halfmove clock"
+	FieldFullmove  = "fullmove number"
+)
+
+// FENError reports which field of a FEN could not be parsed
+type FENError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *FENError) Error() string {
+	return fmt.Sprintf("invalid FEN %s %q: %v", e.Field, e.Value, e.Err)
+}
+
+func (e *FENError) Unwrap() error {
+	return e.Err
+}
+
+// ParseFEN parses all six fields of a FEN
+// The two move clocks may be left off, in which case they default to "0 1"
+func ParseFEN(fen string) (*Position, error) {
+
+	f := strings.Fields(fen)
+	if len(f) == 4 {
+		f = append(f, "0", "1")
+	}
+	if len(f) != 6 {
+		return nil, &FENError{FieldCount, fen, fmt.Errorf("expected 6 fields, got %d", len(f))}
+	}
+
+	p := &Position{enPassant: noSquare}
+
+	// Piece placement
+	b, err := newBoard(f[0])
+	if err != nil {
+		return nil, &FENError{FieldPlacement, f[0], err}
+	}
+	if err = validatePlacement(*b); err != nil {
+		return nil, &FENError{FieldPlacement, f[0], err}
+	}
+	p.brd = *b
+
+	// Side to move
+	switch f[1] {
+	case "w":
+		p.whiteTurn = true
+	case "b":
+		p.whiteTurn = false
+	default:
+		return nil, &FENError{FieldSide, f[1], fmt.Errorf("expected w or b")}
+	}
+
+	// Castling rights
+	if err = p.parseCastling(f[2]); err != nil {
+		return nil, &FENError{FieldCastling, f[2], err}
+	}
+
+	// En passant target square
+	if err = p.parseEnPassant(f[3]); err != nil {
+		return nil, &FENError{FieldEnPassant, f[3], err}
+	}
+
+	// Move clocks
+	p.halfmove, err = strconv.Atoi(f[4])
+	if err != nil || p.halfmove < 0 {
+		return nil, &FENError{FieldHalfmove, f[4], fmt.Errorf("expected a non-negative number")}
+	}
+	p.fullmove, err = strconv.Atoi(f[5])
+	if err != nil || p.fullmove < 1 {
+		return nil, &FENError{FieldFullmove, f[5], fmt.Errorf("expected a positive number")}
+	}
+
+	return p, nil
+}
+
+// Each side needs exactly one king, and pawns cannot stand on the back ranks
+func validatePlacement(b board) error {
+	var wk, bk int
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			switch b[x][y] {
+			case 'K':
+				wk++
+			case 'k':
+				bk++
+			case 'P', 'p':
+				if y == 0 || y == 7 {
+					return fmt.Errorf("pawn on the back rank at %s", squareName(x, y))
+				}
+			}
+		}
+	}
+	if wk != 1 || bk != 1 {
+		return fmt.Errorf("expected one king per side, found %d white and %d black", wk, bk)
+	}
+	return nil
+}
+
+// Castling rights are KQkq, with a file letter in place of K or Q when the rook
+// is not the outermost one on that side of the king, as in X-FEN and Shredder-FEN
+func (p *Position) parseCastling(s string) error {
+	p.castleFiles = standardCastleFiles
+	if s == "-" {
+		return nil
+	}
+	for _, c := range s {
+		white := unicode.IsUpper(c)
+		y := homeRank(white)
+		kx := p.brd.kingFile(white)
+
+		rx := -1
+		switch lower := unicode.ToLower(c); {
+		case lower == 'k':
+			rx = p.brd.outerRook(kx, y, 1, white)
+		case lower == 'q':
+			rx = p.brd.outerRook(kx, y, -1, white)
+		case lower >= 'a' && lower <= 'h':
+			rx = int(lower - 'a')
+		default:
+			return fmt.Errorf("unexpected char %s", string(c))
+		}
+
+		// Rights are only possible while the king and rook are on their home rank
+		if kx < 0 || rx < 0 || rx == kx || p.brd[rx][y] != colored('r', white) {
+			return fmt.Errorf("%s has no king and rook on their home squares", string(c))
+		}
+		i := castleRight(white, rx > kx)
+		if p.castling[i] {
+			return fmt.Errorf("duplicate char %s", string(c))
+		}
+		p.castling[i] = true
+		p.castleFiles[i] = rx
+		if kx != 4 || rx != standardCastleFiles[i] {
+			p.chess960 = true
+		}
+	}
+	return nil
+}
+
+func (p *Position) parseEnPassant(s string) error {
+	if s == "-" {
+		return nil
+	}
+	x, y, err := parseSquare(s)
+	if err != nil {
+		return err
+	}
+	// The pawn that skipped over the square belongs to the side not to move
+	if p.whiteTurn && y != 2 || !p.whiteTurn && y != 5 {
+		return fmt.Errorf("target square is on the wrong rank for the side to move")
+	}
+	p.enPassant = [2]int{x, y}
+	return nil
+}
+
+// Castling rooks start in the corners in standard chess, indexed like Position.castling
+var standardCastleFiles = [4]int{castleWK: 7, castleWQ: 0, castleBK: 7, castleBQ: 0}
+
+// Index into Position.castling of the right to castle on the given side
+func castleRight(white bool, kingside bool) int {
+	i := castleWK
+	if !kingside {
+		i += castleWQ - castleWK
+	}
+	if !white {
+		i += castleBK - castleWK
+	}
+	return i
+}
+
+// Rank the king and rooks of a side castle on, which is 7 for White's first rank
+func homeRank(white bool) int {
+	if white {
+		return 7
+	}
+	return 0
+}
+
+// Return the file of the side's king on its home rank, or -1
+func (b *board) kingFile(white bool) int {
+	for x := 0; x < 8; x++ {
+		if b[x][homeRank(white)] == colored('k', white) {
+			return x
+		}
+	}
+	return -1
+}
+
+// Return the file of the side's rook furthest from the king in the given direction, or -1
+func (b *board) outerRook(kx int, y int, step int, white bool) int {
+	if kx < 0 {
+		return -1
+	}
+	rx := -1
+	for x := kx + step; inBounds(x, y); x += step {
+		if b[x][y] == colored('r', white) {
+			rx = x
+		}
+	}
+	return rx
+}
+
+// #######################################################################
+// (Section 3) Serializing ###############################################
+// #######################################################################
+
+// FEN returns the position as a six field FEN
+func (p *Position) FEN() string {
+	var sb strings.Builder
+
+	sb.WriteString(p.brd.placement())
+
+	if p.whiteTurn {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	castling := ""
+	for i, ok := range p.castling {
+		if !ok {
+			continue
+		}
+		// A rook that is not the outermost one on its side is named by its file
+		white, step := i < castleBK, 1
+		if i == castleWQ || i == castleBQ {
+			step = -1
+		}
+		y := homeRank(white)
+		if p.brd.outerRook(p.brd.kingFile(white), y, step, white) == p.castleFiles[i] {
+			castling += string("KQkq"[i])
+		} else {
+			castling += string(colored(rune('a'+p.castleFiles[i]), white))
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	sb.WriteString(castling)
+
+	if p.enPassant == noSquare {
+		sb.WriteString(" -")
+	} else {
+		sb.WriteString(" " + squareName(p.enPassant[0], p.enPassant[1]))
+	}
+
+	fmt.Fprintf(&sb, " %d %d", p.halfmove, p.fullmove)
+	return sb.String()
+}
+
+// placement returns the piece placement field of a FEN
+func (b board) placement() string {
+	var sb strings.Builder
+	for y := 0; y < 8; y++ {
+		empty := 0
+		for x := 0; x < 8; x++ {
+			if b[x][y] == '-' {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteRune(b[x][y])
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if y < 7 {
+			sb.WriteString("/")
+		}
+	}
+	return sb.String()
+}
+
+// #######################################################################
+// (Section 4) Helper Functions ##########################################
+// #######################################################################
+
+// squareName converts board coordinates into algebraic notation, e.g. 4,6 is e2
+func squareName(x int, y int) string {
+	return string([]byte{byte('a' + x), byte('8' - y)})
+}
+
+// parseSquare converts algebraic notation into board coordinates
+func parseSquare(s string) (int, int, error) {
+	if len(s) != 2 {
+		return 0, 0, fmt.Errorf("invalid square %s", s)
+	}
+	x, y := int(s[0])-'a', int('8'-int(s[1]))
+	if !inBounds(x, y) {
+		return 0, 0, fmt.Errorf("invalid square %s", s)
+	}
+	return x, y, nil
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFENRoundTrip(t *testing.T) {
+
+	fens := []string{
+		StartFEN,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"4k3/8/8/8/8/8/8/4K2R b K - 37 80",
+	}
+	for _, fen := range fens {
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Error(fen, err)
+			continue
+		}
+		if p.FEN() != fen {
+			t.Error("expected ", fen, " got ", p.FEN())
+		}
+	}
+
+	// The move clocks are optional
+	p, err := ParseFEN("4k3/8/8/8/8/8/8/4K3 w - -")
+	if err != nil {
+		t.Error(err)
+	} else if p.FEN() != "4k3/8/8/8/8/8/8/4K3 w - - 0 1" {
+		t.Error("expected default clocks, got ", p.FEN())
+	}
+}
+
+func TestFENErrors(t *testing.T) {
+
+	invalid := map[string]string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -  0":      FieldCount,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 x":   FieldCount,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN w KQkq - 0 1":      FieldPlacement,
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":     FieldPlacement,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNRR w KQkq - 0 1":    FieldPlacement,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQQBNR w kq - 0 1":       FieldPlacement,
+		"pnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1":       FieldPlacement,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1":     FieldSide,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1":     FieldCastling,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKQkq - 0 1":    FieldCastling,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1":     FieldCastling,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1":    FieldEnPassant,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e3 0 1":    FieldEnPassant,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1":    FieldHalfmove,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0":     FieldFullmove,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 first": FieldFullmove,
+	}
+	for fen, field := range invalid {
+		_, err := ParseFEN(fen)
+		var fenErr *FENError
+		if !errors.As(err, &fenErr) {
+			t.Error(fen, " expects FENError, got ", err)
+			continue
+		}
+		if fenErr.Field != field {
+			t.Error(fen, " expects error in ", field, " got ", fenErr)
+		}
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+
+	p, _ := ParseFEN(StartFEN)
+	moves := []string{"e2e4", "c7c5", "g1f3", "d7d6", "h1g1", "e8d7"}
+	expected := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2",
+		"rnbqkbnr/pp1ppppp/8/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+		"rnbqkbnr/pp2pppp/3p4/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 3",
+		"rnbqkbnr/pp2pppp/3p4/2p5/4P3/5N2/PPPP1PPP/RNBQKBR1 b Qkq - 1 3",
+		"rnbq1bnr/pp1kpppp/3p4/2p5/4P3/5N2/PPPP1PPP/RNBQKBR1 w Q - 2 4",
+	}
+	for i, x := range moves {
+		if _, err := makeMove(p, x, p.WhiteTurn()); err != nil {
+			t.Error(x, err)
+			break
+		}
+		if p.FEN() != expected[i] {
+			t.Error(x, " expected ", expected[i], " got ", p.FEN())
+		}
+	}
+}
@@ -1,10 +1,10 @@
 package game
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
 	"strings"
+	"time"
 )
 
 // #######################################################################
@@ -12,191 +12,448 @@ import (
 // #######################################################################
 
 type GameState struct {
-	brd       *board
-	whiteTurn bool
-	reader    *bufio.Reader
-	rch       chan string
-	wch       chan string
+	pos         *Position
+	start       Position       // Position the game started from
+	history     []undo         // Moves played since the start, and how to take them back
+	redos       []Move         // Moves taken back, the last one first to be played again
+	white       bool           // The local player's color in a P2P game or against the computer
+	repetitions map[uint64]int // Number of times each position has occurred, by hash
+	tags        PGNTags        // Headers of the game's PGN
+	pgnDir      string         // Directory finished games are saved in, or empty to not save them
+	clock       *Clock         // Nil if the game is untimed
+	opponent    Analyser       // The computer opponent, or nil
+	search      SearchParams   // How long the computer thinks about its moves
+	analyser    Analyser       // Engine giving hints, or nil until the first hint
+	book        *Book          // Opening book, or nil
+	in          MoveSource     // Where the local player's moves come from
+	out         Renderer       // Where the game is shown
+	rch         chan string
+	wch         chan string
+}
+
+type HotseatParams struct {
+	FEN         string      // Starting position, the standard one if empty
+	Tags        PGNTags     // Headers of the game's PGN
+	PGNDir      string      // Directory to save the finished game in, or empty to not save it
+	TimeControl TimeControl // Untimed if the base is zero
+	Analyser    Analyser    // Engine giving hints, the built-in one if nil
+	Book        *Book       // Opening book to show book moves from, or nil
+	Input       MoveSource  // Where the players' moves come from, the terminal if nil
+	Output      Renderer    // Where the game is shown, the terminal if nil
 }
 
 type P2PParams struct {
-	YouStart  bool
-	ReadChan  chan string
-	WriteChan chan string
+	YouStart    bool        // True if you play White
+	FEN         string      // Starting position, the standard one if empty
+	Tags        PGNTags     // Headers of the game's PGN
+	PGNDir      string      // Directory to save the finished game in, or empty to not save it
+	TimeControl TimeControl // Untimed if the base is zero, both players must agree on it
+	Book        *Book       // Opening book to note in the PGN when the game leaves it, or nil
+	Input       MoveSource  // Where your moves come from, the terminal if nil
+	Output      Renderer    // Where the game is shown, the terminal if nil
+	ReadChan    chan string
+	WriteChan   chan string
 }
 
-func InitHotseat() (*GameState, error) {
-	// Create our game broad
-	b, err := defaultBoard()
+func InitHotseat(p HotseatParams) (*GameState, error) {
+	// Create our game position
+	pos, err := startPosition(p.FEN)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return &GameState{
-		brd:       b,
-		whiteTurn: true, // White always starts first
-		reader:    bufio.NewReader(os.Stdin),
-	}, nil
+	gs := &GameState{
+		pos:         pos,
+		start:       *pos,
+		white:       true,
+		repetitions: make(map[uint64]int),
+		tags:        p.Tags.withDefaults(time.Now()),
+		pgnDir:      p.PGNDir,
+		clock:       newGameClock(p.TimeControl),
+		analyser:    p.Analyser,
+		book:        p.Book,
+	}
+	gs.in, gs.out = withTerminal(p.Input, p.Output)
+	gs.recordPosition()
+	return gs, nil
 }
 
 func InitP2P(p P2PParams) (*GameState, error) {
-	// Create our game broad
-	b, err := defaultBoard()
+	// Create our game position
+	pos, err := startPosition(p.FEN)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return &GameState{
-		brd:       b,
-		whiteTurn: p.YouStart,
-		reader:    bufio.NewReader(os.Stdin),
-		rch:       p.ReadChan,
-		wch:       p.WriteChan,
-	}, nil
+	gs := &GameState{
+		pos:         pos,
+		start:       *pos,
+		white:       p.YouStart,
+		repetitions: make(map[uint64]int),
+		tags:        p.Tags.withDefaults(time.Now()),
+		pgnDir:      p.PGNDir,
+		clock:       newGameClock(p.TimeControl),
+		book:        p.Book,
+		rch:         p.ReadChan,
+		wch:         p.WriteChan,
+	}
+	gs.in, gs.out = withTerminal(p.Input, p.Output)
+	gs.recordPosition()
+	return gs, nil
 }
 
-// #######################################################################
-// (Section 2) Turns #####################################################
-// #######################################################################
+func startPosition(fen string) (*Position, error) {
+	if fen == "" {
+		fen = StartFEN
+	}
+	return ParseFEN(fen)
+}
 
-func readYourMove(reader *bufio.Reader) (string, error) {
-	fmt.Print("Enter move: ")
-	// ReadString will block until the delimiter is entered
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Println("An error occured while reading input. Please try again", err)
-		return input, fmt.Errorf("cannot read move: %w", err)
+// Return the clock for a time control, or nil for an untimed game
+func newGameClock(tc TimeControl) *Clock {
+	if tc.Base == 0 {
+		return nil
 	}
+	return NewClock(tc)
+}
 
-	// Remove the delimeter from the string
-	input = strings.TrimSuffix(input, "\n")
-	input = strings.TrimSuffix(input, "\r")
+// FEN returns the current position of the game as a FEN
+func (gs *GameState) FEN() string {
+	return gs.pos.FEN()
+}
 
-	// fmt.Println("input: (", input, ")")
-	return input, nil
+// Position returns a copy of the current position of the game
+func (gs *GameState) Position() Position {
+	return *gs.pos
 }
 
-func (gs *GameState) yourTurn() (bool, string) {
+// #######################################################################
+// (Section 2) Turns #####################################################
+// #######################################################################
+
+func (gs *GameState) yourTurn() (Outcome, string) {
 
 	var err error
 	var move string
-	var checkmate bool
+	var outcome Outcome
 
 	for {
 		// Read Move
-		move, err = readYourMove(gs.reader)
+		move, err = gs.readMove()
+		if errors.Is(err, ErrorTimeout) {
+			gs.out.Message("Your time is up!")
+			return gs.timeout(), flagMessage
+		}
 		if err != nil {
-			fmt.Println("Error: ", err, "Move: ", move)
-			fmt.Println("Please input a valid move:")
+			gs.out.Message(fmt.Sprintf("Error: %v Move: %s", err, move))
+			gs.out.Message("Please input a valid move:")
 			continue
 		}
 		if move == "quit" || move == "q" {
-			return false, move
+			return winFor(!gs.pos.whiteTurn, Resignation), move
+		}
+		if move == "fen" {
+			gs.out.Message(gs.FEN())
+			continue
+		}
+		if move == "moves" || move == "history" {
+			gs.out.Moves(gs.view())
+			continue
+		}
+		if move == "hint" {
+			if gs.rch != nil {
+				gs.out.Message("Hints are not available in P2P games.")
+				continue
+			}
+			gs.hint()
+			continue
+		}
+		if move == "book" {
+			if gs.rch != nil {
+				gs.out.Message("Book moves are not shown in P2P games.")
+				continue
+			}
+			gs.bookHint()
+			continue
+		}
+		if move == "pgn" {
+			gs.out.Message(strings.TrimSuffix(gs.PGN(Outcome{}), "\n"))
+			continue
+		}
+		if move == "undo" || move == "redo" {
+			if gs.rch != nil {
+				// Over P2P, a move can only be taken back if your opponent accepts
+				if move == "redo" {
+					gs.out.Message("Redo is not available over P2P.")
+					continue
+				}
+				if !gs.canTakeBack() {
+					gs.out.Message("You have no move to take back.")
+					continue
+				}
+				return Outcome{}, takebackRequest
+			}
+			if gs.opponent != nil && move == "undo" {
+				// Against the computer, its reply is taken back too so it is your turn again
+				if !gs.canTakeBack() {
+					gs.out.Message("You have no move to take back.")
+					continue
+				}
+				gs.takeBack()
+				gs.printBoard()
+				return Outcome{}, move
+			}
+			if move == "undo" && !gs.undo() {
+				gs.out.Message("There is no move to undo.")
+				continue
+			}
+			if move == "redo" && !gs.redo() {
+				gs.out.Message("There is no move to redo.")
+				continue
+			}
+			if move == "redo" && gs.clock != nil {
+				gs.clock.Switch()
+			}
+			gs.printBoard()
+			outcome, _ = gs.outcome()
+			return outcome, move
+		}
+		if move == "draw" {
+			outcome = gs.claimDraw()
+			if !outcome.Over() {
+				gs.out.Message("There is no draw to claim.")
+				gs.out.Message("Please input a valid move:")
+				continue
+			}
+			return outcome, move
 		}
 		// Verify and Make Move
-		err = makeMove(gs.brd, move, gs.whiteTurn)
+		err = gs.play(move)
 		if err != nil {
-			fmt.Println("Error: ", err)
-			fmt.Println("Please input a valid move:")
+			gs.out.Message(fmt.Sprint("Error: ", err))
+			gs.out.Message("Please input a valid move:")
 			continue
 		}
-		gs.brd.printBoard()
+		if gs.clock != nil {
+			// Your opponent is told how long you took, so the time it takes
+			// your move to reach them is not charged to you
+			used := gs.clock.Elapsed()
+			gs.clock.Press(used)
+			if gs.rch != nil {
+				move = fmt.Sprintf("%s@%d", move, used.Milliseconds())
+			}
+		}
+		gs.printBoard()
 		// Report Check/Checkmate and if Game is Complete
-		checkmate, err = reportCheckAndCheckmate(*gs.brd)
+		outcome, err = gs.afterMove()
 		if err != nil {
-			fmt.Println("Error: ", err)
-			fmt.Println("Please input a valid move:")
+			gs.out.Message(fmt.Sprint("Error: ", err))
+			gs.out.Message("Please input a valid move:")
 			continue
 		}
-		return !checkmate, move
+		return outcome, move
 	}
 }
 
-func (gs *GameState) theirTurn(move string) bool {
+func (gs *GameState) theirTurn(move string) Outcome {
 
-	var checkmate bool
+	var outcome Outcome
 
+	if move == flagMessage {
+		gs.out.Message("Your opponent ran out of time!")
+		return gs.timeout()
+	}
 	if move == "quit" || move == "q" {
-		return false
+		return winFor(!gs.pos.whiteTurn, Resignation)
+	}
+	if move == "draw" {
+		outcome = gs.claimDraw()
+		if !outcome.Over() {
+			gs.out.Message("They claimed a draw they are not entitled to...")
+			panic(fmt.Errorf("invalid draw claim"))
+		}
+		return outcome
+	}
+	var used time.Duration
+	if gs.clock != nil {
+		move, used = gs.timedMove(move)
+		if used > gs.clock.Allowed() {
+			gs.out.Message("Your opponent ran out of time!")
+			return gs.timeout()
+		}
 	}
 	// Verify and Make Move
-	err := makeMove(gs.brd, move, !gs.whiteTurn)
+	err := gs.play(move)
 	if err != nil {
-		fmt.Println("They gave you a bad input... (", move, ")")
+		gs.out.Message(fmt.Sprintf("They gave you a bad input... (%s)", move))
 		panic(err)
 	}
-	gs.brd.printBoard()
+	if gs.clock != nil {
+		gs.clock.Press(used)
+	}
+	gs.printBoard()
 	// Report Check/Checkmate and if Game is Complete
-	checkmate, err = reportCheckAndCheckmate(*gs.brd)
+	outcome, err = gs.afterMove()
 	if err != nil {
-		fmt.Println("They gave you a bad input... (", move, ")")
+		gs.out.Message(fmt.Sprintf("They gave you a bad input... (%s)", move))
 		panic(err)
 	}
-	return !checkmate
+	return outcome
+}
+
+// Make the move for the side to move and add it to the history
+func (gs *GameState) play(move string) error {
+	u := newUndo(gs.pos)
+	m, err := makeMove(gs.pos, move, gs.pos.whiteTurn)
+	if err != nil {
+		return err
+	}
+	u.move = m
+	if gs.clock != nil {
+		u.clocks = gs.clock.remaining
+	}
+	gs.history = append(gs.history, u)
+	// A new move replaces the moves that were taken back
+	gs.redos = nil
+	return nil
+}
+
+// Record the position reached by a move, then report check, checkmate, and draws
+func (gs *GameState) afterMove() (Outcome, error) {
+	gs.recordPosition()
+	return gs.outcome()
+}
+
+// Report check, checkmate, and draws in the current position
+func (gs *GameState) outcome() (Outcome, error) {
+
+	outcome, report, err := reportCheckAndCheckmate(*gs.pos)
+	if report != "" {
+		gs.out.Message(report)
+	}
+	if err != nil || outcome.Over() {
+		return outcome, err
+	}
+
+	outcome = gs.automaticDraw()
+	if outcome.Over() {
+		gs.out.Message(fmt.Sprintf("The game is drawn by %s!", outcome.Reason))
+	} else if gs.claimDraw().Over() {
+		gs.out.Message(fmt.Sprintf("A draw can be claimed by %s, type \"draw\" to claim it.", gs.claimDraw().Reason))
+	}
+	return outcome, nil
 }
 
 // #######################################################################
 // (Section 3) Main Game Functions/Loops #################################
 // #######################################################################
 
-func (gs *GameState) PlayHotseat() {
+func (gs *GameState) PlayHotseat() Outcome {
 
-	fmt.Println("----- Hotsteat Chess Game -----")
-	fmt.Println("For a p2p game or game instructions, see `./chess -help`.")
-	gs.brd.printBoard()
+	gs.out.Message("----- Hotsteat Chess Game -----")
+	gs.out.Message("For a p2p game or game instructions, see `./chess -help`.")
+	if gs.clock != nil {
+		gs.clock.Start(gs.pos.whiteTurn)
+	}
+	gs.printBoard()
 
-	playing := true
-	for playing {
-		if gs.whiteTurn {
-			fmt.Println("White's Turn")
+	// A game started from a FEN could already be over
+	outcome, err := gs.outcome()
+	if err != nil {
+		panic(err)
+	}
+	for !outcome.Over() {
+		if gs.pos.whiteTurn {
+			gs.out.Message("White's Turn")
 		} else {
-			fmt.Println("Black's Turn")
+			gs.out.Message("Black's Turn")
 		}
-		playing, _ = gs.yourTurn()
-		gs.whiteTurn = !gs.whiteTurn
+		outcome, _ = gs.yourTurn()
 	}
-	fmt.Println("Game End")
+	gs.out.GameOver(outcome)
+	gs.saveFinishedGame(outcome)
+
+	return outcome
 }
 
-func (gs *GameState) PlayP2P() (bool, bool) {
+func (gs *GameState) PlayP2P() Outcome {
 
 	defer close(gs.rch)
 	defer close(gs.wch)
 
-	fmt.Println("----- P2P Chess Game -----")
-	fmt.Println("For a hotseat game or game instructions, see `./chess -help`.")
-	gs.brd.printBoard()
+	gs.out.Message("----- P2P Chess Game -----")
+	gs.out.Message("For a hotseat game or game instructions, see `./chess -help`.")
+	if gs.clock != nil {
+		gs.clock.Start(gs.pos.whiteTurn)
+	}
+	gs.printBoard()
+
+	// A game started from a FEN could already be over
+	outcome, err := gs.outcome()
+	if err != nil {
+		panic(err)
+	}
 
 	var move string
-	turn := gs.whiteTurn
-	playing := true
-	for playing {
+	turn := gs.pos.whiteTurn == gs.white
+	for !outcome.Over() {
 		if turn {
-			fmt.Println("Your Turn")
+			gs.out.Message("Your Turn")
 			// Make your turn locally
-			playing, move = gs.yourTurn()
+			outcome, move = gs.yourTurn()
 			// Send your move to your opponent
 			gs.wch <- move
+			if move == takebackRequest {
+				// Block until your opponent answers, then it is still your turn
+				// Your clock is stopped while you wait
+				resume := gs.pauseClock()
+				if <-gs.rch == takebackAccept {
+					gs.takeBack()
+					gs.out.Message("Your opponent accepted the takeback.")
+					gs.printBoard()
+				} else {
+					resume()
+					gs.out.Message("Your opponent declined the takeback.")
+				}
+				continue
+			}
 		} else {
-			fmt.Println("Opponents Turn")
-			// Block until your opponent sends their move
-			move = <-gs.rch
+			gs.out.Message("Opponents Turn")
+			// Block until your opponent sends their move, or their flag falls
+			move, err = gs.receiveMove()
+			if err != nil {
+				gs.out.Message("Your opponent ran out of time!")
+				outcome = gs.timeout()
+				break
+			}
+			if move == takebackRequest {
+				// Either way it is still their turn, and their clock is stopped while you answer
+				resume := gs.pauseClock()
+				if gs.in.AcceptTakeback() {
+					gs.wch <- takebackAccept
+					gs.takeBack()
+					gs.printBoard()
+				} else {
+					resume()
+					gs.wch <- takebackDecline
+				}
+				continue
+			}
 			// Make your opponent's move locally
-			playing = gs.theirTurn(move)
-			fmt.Println("Their move: ", move)
+			outcome = gs.theirTurn(move)
+			gs.out.Message("Their move: " + move)
 		}
 		turn = !turn
 	}
 
-	fmt.Println("Game End")
+	gs.out.GameOver(outcome)
 
-	if !turn {
-		fmt.Println("~~~You Win!~~~")
+	if outcome.Result == Draw {
+		gs.out.Message("~~~Draw~~~")
+	} else if outcome.Won(gs.white) {
+		gs.out.Message("~~~You Win!~~~")
 	} else {
-		fmt.Println("~~~You Lose~~~")
-	}
-
-	if move == "quit" || move == "q" {
-		return false, false
+		gs.out.Message("~~~You Lose~~~")
 	}
+	gs.saveFinishedGame(outcome)
 
-	return true, !turn
+	return outcome
 }
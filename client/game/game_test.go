@@ -1,29 +1,79 @@
 package game
 
 import (
-	"bufio"
+	"os"
 	"strings"
 	"testing"
 )
 
 func TestHotseatGame(t *testing.T) {
 
-	g, err := InitHotseat()
+	g, err := InitHotseat(HotseatParams{})
 	if err != nil {
 		t.Error(err)
 	}
-	g.reader = bufio.NewReader(strings.NewReader("f2f3\ne7e5\ng2g4\nd8h4\nq\n"))
-	g.PlayHotseat()
+	g.in = NewTerminal(strings.NewReader("f2f3\ne7e5\ng2g4\nd8h4\nq\n"), os.Stdout)
+	if o := g.PlayHotseat(); o != (Outcome{BlackWins, Checkmate}) {
+		t.Error("expected black to win by checkmate, got ", o)
+	}
+
+	g, err = InitHotseat(HotseatParams{})
+	if err != nil {
+		t.Error(err)
+	}
+	g.in = NewTerminal(strings.NewReader("q\n"), os.Stdout)
+	if o := g.PlayHotseat(); o != (Outcome{BlackWins, Resignation}) {
+		t.Error("expected white to resign, got ", o)
+	}
 
-	g, err = InitHotseat()
+	// Stalemate is a draw
+	g, err = InitHotseat(HotseatParams{FEN: "7k/8/5Q2/8/8/8/8/K7 w - - 0 1"})
 	if err != nil {
 		t.Error(err)
 	}
-	g.reader = bufio.NewReader(strings.NewReader("q\n"))
-	g.PlayHotseat()
+	g.in = NewTerminal(strings.NewReader("f6f7\n"), os.Stdout)
+	if o := g.PlayHotseat(); o != (Outcome{Draw, Stalemate}) {
+		t.Error("expected stalemate, got ", o)
+	}
 
 }
 
+func TestDrawRules(t *testing.T) {
+
+	shuffle := "g1f3\ng8f6\nf3g1\nf6g8\n"
+
+	games := []struct {
+		fen      string
+		input    string
+		expected Outcome
+	}{
+		// Claiming too early is refused, then the third occurrence can be claimed
+		{"", "draw\n" + shuffle + "draw\n" + shuffle + "draw\n", Outcome{Draw, ThreefoldRepetition}},
+		// The fifth occurrence ends the game without a claim
+		{"", shuffle + shuffle + shuffle + shuffle + "q\n", Outcome{Draw, FivefoldRepetition}},
+		{"4k3/8/8/8/8/8/8/4K2R w - - 99 80", "draw\nh1h2\ndraw\n", Outcome{Draw, FiftyMoveRule}},
+		{"4k3/8/8/8/8/8/8/4K2R w - - 149 80", "h1h2\nq\n", Outcome{Draw, SeventyFiveMoveRule}},
+		// A capture resets the count
+		{"4k3/8/8/8/8/8/7r/4K2R w - - 149 80", "h1h2\nq\n", Outcome{WhiteWins, Resignation}},
+		// Checkmate takes precedence over the seventy-five-move rule
+		{"7k/8/6K1/8/8/8/8/R7 w - - 149 80", "a1a8\nq\n", Outcome{WhiteWins, Checkmate}},
+		// Capturing the last piece that could checkmate
+		{"4k3/8/8/8/8/8/4q3/4K3 w - - 0 1", "e1e2\nq\n", Outcome{Draw, InsufficientMaterial}},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "q\n", Outcome{Draw, InsufficientMaterial}},
+	}
+	for _, x := range games {
+		g, err := InitHotseat(HotseatParams{FEN: x.fen})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		g.in = NewTerminal(strings.NewReader(x.input), os.Stdout)
+		if o := g.PlayHotseat(); o != x.expected {
+			t.Error(x.fen, " expected ", x.expected, " got ", o)
+		}
+	}
+}
+
 func TestP2pGame(t *testing.T) {
 
 	rch, wch := make(chan string, 1), make(chan string, 1)
@@ -34,14 +84,47 @@ func TestP2pGame(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
-	g.reader = bufio.NewReader(strings.NewReader("q\n"))
+	g.in = NewTerminal(strings.NewReader("q\n"), os.Stdout)
 
+	done := make(chan bool)
 	go func() {
 		rch <- "f2f3"
 		<-wch
-		rch <- "g2g4"
-		<-wch
+		close(done)
+	}()
+
+	if o := g.PlayP2P(); o != (Outcome{WhiteWins, Resignation}) {
+		t.Error("expected black to resign, got ", o)
+	}
+	<-done
+}
+
+func TestP2pPromotion(t *testing.T) {
+
+	rch, wch := make(chan string, 1), make(chan string, 1)
+	g, err := InitP2P(P2PParams{
+		YouStart:  true,
+		FEN:       "4k3/P7/8/8/8/8/7P/4K3 w - - 0 1",
+		ReadChan:  rch,
+		WriteChan: wch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("a7a8n\nq\n"), os.Stdout)
+
+	// The peer must receive the promotion exactly as it was typed
+	sent := make(chan string, 2)
+	go func() {
+		sent <- <-wch
+		rch <- "e8e7"
+		sent <- <-wch
 	}()
 
 	g.PlayP2P()
+	if move := <-sent; move != "a7a8n" {
+		t.Error("expected a7a8n to be sent, got ", move)
+	}
+	if g.FEN() != "N7/4k3/8/8/8/8/7P/4K3 w - - 1 2" {
+		t.Error("unexpected position ", g.FEN())
+	}
 }
@@ -0,0 +1,366 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The rules of chess applied to the rune grid, one piece at a time
+// The game plays with the bitboards, and these are kept to check them against
+
+// Return every legal move by validating each candidate move on the rune grid
+// This is much slower than LegalMoves, and written separately from it,
+// so the two generators can check each other
+func gridLegalMoves(p *Position) []Move {
+
+	var moves []Move
+	b := p.brd
+
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			piece := b[x][y]
+			if piece == '-' || unicode.IsUpper(piece) != p.whiteTurn {
+				continue
+			}
+			// Only squares the piece could reach on an empty board are validated
+			squares := candidateSquares(b, x, y)
+			if unicode.ToLower(piece) == 'k' {
+				// A Chess960 rook can be next to the king, where it is already a candidate
+				for _, sq := range p.castleSquares(x, y) {
+					if sq[0]-x > 1 || x-sq[0] > 1 {
+						squares = append(squares, sq)
+					}
+				}
+			}
+			for _, to := range squares {
+				m := p.newMove(x, y, to[0], to[1], 0)
+				promotions := []rune{0}
+				if isPromotion(m) {
+					promotions = []rune{'q', 'r', 'b', 'n'}
+				}
+				for _, promotion := range promotions {
+					if promotion != 0 {
+						m.promotion = colored(promotion, p.whiteTurn)
+					}
+					if ok, _ := validateMove(m); ok {
+						moves = append(moves, toMove(m))
+					}
+				}
+			}
+		}
+	}
+
+	return moves
+}
+
+// Return the squares the piece at x, y could move to, ignoring check
+func candidateSquares(b board, x int, y int) [][2]int {
+
+	var squares [][2]int
+	add := func(x2 int, y2 int) {
+		if inBounds(x2, y2) {
+			squares = append(squares, [2]int{x2, y2})
+		}
+	}
+
+	switch unicode.ToLower(b[x][y]) {
+	case 'p', 'n', 'k':
+		for _, i := range getDirections(b[x][y]) {
+			add(x-i[0], y-i[1])
+		}
+	default:
+		for _, i := range getDirections(b[x][y]) {
+			cx, cy := x+i[0], y+i[1]
+			for inBounds(cx, cy) {
+				add(cx, cy)
+				if b[cx][cy] != '-' {
+					break
+				}
+				cx += i[0]
+				cy += i[1]
+			}
+		}
+	}
+
+	return squares
+}
+
+// Return the squares the king at x, y moves to when castling,
+// which in Chess960 are the squares of its rooks
+func (p *Position) castleSquares(x int, y int) [][2]int {
+	var squares [][2]int
+	for i, rx := range p.castleFiles {
+		x2 := x + 2
+		if i == castleWQ || i == castleBQ {
+			x2 = x - 2
+		}
+		if p.chess960 {
+			x2 = rx
+		}
+		if p.castling[i] && (i < castleBK) == p.whiteTurn && inBounds(x2, y) {
+			squares = append(squares, [2]int{x2, y})
+		}
+	}
+	return squares
+}
+
+// Convert a validated move into a Move
+func toMove(m move) Move {
+	mv := Move{
+		From:      Square{m.x1, m.y1},
+		To:        Square{m.x2, m.y2},
+		Piece:     m.startPiece,
+		Promotion: m.promotion,
+	}
+	if m.endPiece != '-' && !isCastle(m) {
+		mv.Capture = m.endPiece
+	}
+	switch {
+	case isEnPassant(m):
+		mv.Flags |= EnPassant
+		mv.Capture = colored('p', !m.white)
+	case isCastle(m) && m.x2 > m.x1:
+		mv.Flags |= KingsideCastle
+	case isCastle(m):
+		mv.Flags |= QueensideCastle
+	case unicode.ToLower(m.startPiece) == 'p' && (m.y1-m.y2 == 2 || m.y2-m.y1 == 2):
+		mv.Flags |= DoublePush
+	}
+	return mv
+}
+
+// Return true if the move is valid, return false otherwise
+func validateMove(m move) (bool, error) {
+
+	// Return if start is not my color OR if end is my color
+	validStart := strings.Contains("prnbqkPRNBQK", string(m.startPiece)) && (m.white && unicode.IsUpper(m.startPiece) || !m.white && unicode.IsLower(m.startPiece))
+	validEnd := m.endPiece == '-' || m.white && unicode.IsLower(m.endPiece) || !m.white && unicode.IsUpper(m.endPiece)
+	// A Chess960 king castles by moving onto its own rook
+	validEnd = validEnd || isCastle(m)
+	if !validStart || !validEnd {
+		return false, fmt.Errorf("invalid start or invalid end")
+	}
+
+	// Only pawns reaching the last rank promote, and they always have to
+	if isPromotion(m) && m.promotion == 0 {
+		return false, fmt.Errorf("pawn must be promoted on the last rank, e.g. e7e8q")
+	} else if !isPromotion(m) && m.promotion != 0 {
+		return false, fmt.Errorf("only a pawn reaching the last rank can be promoted")
+	}
+
+	// Check rules for specific pieces
+	var valid bool
+	var err error
+	switch m.startPiece {
+	case 'P', 'p':
+
+		// Extra rules for pawn
+		double := m.y1-m.y2 == 2 || m.y2-m.y1 == 2
+		if double && (m.white && m.y1 != 6 || !m.white && m.y1 != 1) {
+			return false, fmt.Errorf("pawn can only advance 2 squares if it has not already moved")
+		} else if double && m.brd[m.x1][(m.y1+m.y2)/2] != '-' {
+			return false, fmt.Errorf("pawn cannot jump over other pieces")
+		} else if (m.x1-m.x2 != 0 && m.endPiece == '-' && !isEnPassant(m)) || (m.x1-m.x2 == 0 && m.endPiece != '-') {
+			return false, fmt.Errorf("pawn can only attack diagonally and move vertically")
+		} else if m.y1-m.y2 >= 1 && !m.white || m.y1-m.y2 <= -1 && m.white {
+			return false, fmt.Errorf("pawn is going the wrong way")
+		}
+
+		valid, err = validateMoveJump(m)
+	case 'N', 'n':
+		valid, err = validateMoveJump(m)
+	case 'K', 'k':
+		if isCastle(m) {
+			valid, err = validateCastle(m)
+		} else {
+			valid, err = validateMoveJump(m)
+		}
+	case 'Q', 'B', 'q', 'b':
+		valid, err = validateMoveCrawl(m)
+	case 'R', 'r':
+		valid, err = validateMoveCrawl(m)
+	default:
+		return false, fmt.Errorf("not a valid piece type")
+	}
+	if !valid {
+		return valid, err
+	}
+
+	// Verify that this move does NOT put our king into check
+	applyMove(&m.brd, m)
+	if kingAttacked(m.brd, m.white) {
+		return false, fmt.Errorf("cannot put your own king into check")
+	}
+
+	return true, nil
+}
+
+// Pawns, Knights, and Kings jump to a location (as opposed to crawling/sliding)
+func validateMoveJump(m move) (bool, error) {
+	// fmt.Println("coor", m.x1, " ", m.y1, " ", m.x2, " ", m.y2)
+	j := [2]int{m.x1 - m.x2, m.y1 - m.y2}
+	for _, i := range getDirections(m.startPiece) {
+		// fmt.Println("match", i, " ", j)
+		if i == j {
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("%s cannot move there", string(m.startPiece))
+}
+
+// Queen, Bishops, and Rooks crawl/slide across the board
+func validateMoveCrawl(m move) (bool, error) {
+	for _, i := range getDirections(m.startPiece) {
+		x, y := m.x1+i[0], m.y1+i[1]
+		for inBounds(x, y) {
+			// fmt.Println(x, y, m.x1, m.y1, m.x2, m.y2, i)
+			if x == m.x2 && y == m.y2 {
+				// We made it to the endPiece
+				return true, nil
+			} else if m.brd[x][y] != '-' {
+				// We ran into another piece
+				break
+			}
+			x += i[0]
+			y += i[1]
+		}
+	}
+	return false, fmt.Errorf("%s cannot move there", string(m.startPiece))
+}
+
+// The king castles by moving two squares towards one of its rooks,
+// or in Chess960 by moving onto the rook
+// The king and rook cannot have moved, the squares they cross must be empty,
+// and the king cannot castle out of, through, or into check
+func validateCastle(m move) (bool, error) {
+
+	right := castleRight(m.white, castleKingside(m))
+	y, rx := homeRank(m.white), m.castleFiles[right]
+	if !m.castling[right] || m.y1 != y || m.brd[rx][y] != colored('r', m.white) {
+		return false, fmt.Errorf("cannot castle, the king or rook has already moved")
+	}
+	if m.chess960 && m.x2 != rx {
+		return false, fmt.Errorf("cannot castle with a rook that has moved")
+	}
+
+	// Every square the king or rook crosses must be empty, apart from the two of them
+	kx2, rx2 := castleDestinations(right)
+	lo, hi := minInt(m.x1, rx, kx2, rx2), maxInt(m.x1, rx, kx2, rx2)
+	for x := lo; x <= hi; x++ {
+		if x != m.x1 && x != rx && m.brd[x][y] != '-' {
+			return false, fmt.Errorf("cannot castle through other pieces")
+		}
+	}
+
+	// The king cannot leave, cross, or land on an attacked square
+	step := 1
+	if kx2 < m.x1 {
+		step = -1
+	}
+	for x := m.x1; x != kx2+step; x += step {
+		if squareAttacked(m.brd, x, y, !m.white) {
+			return false, fmt.Errorf("cannot castle out of, through, or into check")
+		}
+	}
+
+	return true, nil
+}
+
+// Return true if the king of the given color is attacked
+func kingAttacked(b board, white bool) bool {
+	wk, bk, err := findKings(b)
+	if err != nil {
+		return false
+	}
+	if white {
+		return squareAttacked(b, wk[0], wk[1], false)
+	}
+	return squareAttacked(b, bk[0], bk[1], true)
+}
+
+// Return true if any piece of the given color attacks the square at x, y
+func squareAttacked(b board, x int, y int, white bool) bool {
+
+	// Look for an attacker a jump away, undoing the jump from the square
+	jumpsFrom := func(piece rune, directions [][2]int) bool {
+		for _, i := range directions {
+			if inBounds(x+i[0], y+i[1]) && b[x+i[0]][y+i[1]] == colored(piece, white) {
+				return true
+			}
+		}
+		return false
+	}
+	if jumpsFrom('n', getDirections('N')) || jumpsFrom('k', getDirections('K')) {
+		return true
+	}
+	// A White pawn attacks towards rank 8, which is y 0, so it stands a rank below
+	if white && jumpsFrom('p', [][2]int{{1, 1}, {-1, 1}}) || !white && jumpsFrom('p', [][2]int{{1, -1}, {-1, -1}}) {
+		return true
+	}
+
+	// Look along each line for the first piece, which attacks if it slides that way
+	for _, i := range getDirections('Q') {
+		slider := 'b'
+		if i[0] == 0 || i[1] == 0 {
+			slider = 'r'
+		}
+		cx, cy := x+i[0], y+i[1]
+		for inBounds(cx, cy) && b[cx][cy] == '-' {
+			cx += i[0]
+			cy += i[1]
+		}
+		if inBounds(cx, cy) && (b[cx][cy] == colored(slider, white) || b[cx][cy] == colored('q', white)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A pawn reaching the last rank is promoting
+func isPromotion(m move) bool {
+	return m.startPiece == 'P' && m.y2 == 0 || m.startPiece == 'p' && m.y2 == 7
+}
+
+// Return true if the given side has no legal moves,
+// which is checkmate while in check and stalemate otherwise
+func inCheckmate(p Position, kingcolor bool) bool {
+	p.whiteTurn = kingcolor
+	return len(LegalMoves(&p)) == 0
+}
+
+func getDirections(piece rune) [][2]int {
+	var directions = map[rune][][2]int{'P': {{0, 1}, {0, 2}, {1, 1}, {-1, 1}, {0, -1}, {0, -2}, {1, -1}, {-1, -1}},
+		'N': {{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}},
+		'B': {{1, -1}, {-1, 1}, {1, 1}, {-1, -1}},
+		'R': {{0, 1}, {0, -1}, {1, 0}, {-1, 0}},
+		'Q': {{0, 1}, {0, -1}, {1, 1}, {1, 0}, {1, -1}, {-1, 1}, {-1, 0}, {-1, -1}},
+		'K': {{0, 1}, {0, -1}, {1, 1}, {1, 0}, {1, -1}, {-1, 1}, {-1, 0}, {-1, -1}}}
+
+	if unicode.IsLetter(piece) {
+		return directions[unicode.ToUpper(piece)]
+	} else {
+		return [][2]int{} // TODO Could return error here
+	}
+}
+
+func minInt(xs ...int) int {
+	m := xs[0]
+	for _, x := range xs[1:] {
+		if x < m {
+			m = x
+		}
+	}
+	return m
+}
+
+func maxInt(xs ...int) int {
+	m := xs[0]
+	for _, x := range xs[1:] {
+		if x > m {
+			m = x
+		}
+	}
+	return m
+}
@@ -0,0 +1,55 @@
+package game
+
+import (
+	"fmt"
+)
+
+// #######################################################################
+// (Section 1) Move History ##############################################
+// #######################################################################
+
+// Moves returns the moves played so far
+func (gs *GameState) Moves() []Move {
+	var moves []Move
+	for _, u := range gs.history {
+		moves = append(moves, u.move)
+	}
+	return moves
+}
+
+// SANMoves returns the moves played so far in Standard Algebraic Notation
+func (gs *GameState) SANMoves() []string {
+	var sans []string
+	p := gs.start
+	for _, u := range gs.history {
+		sans = append(sans, p.SAN(u.move))
+		p.Play(u.move)
+	}
+	return sans
+}
+
+// Return the numbered move list, one line per move of each side, e.g. "1. e4 e5"
+func (gs *GameState) moveList() []string {
+	return numberMoves(gs.SANMoves(), gs.start.whiteTurn, gs.start.fullmove)
+}
+
+// Number the moves of a game that starts on the given move
+func numberMoves(sans []string, whiteStarts bool, fullmove int) []string {
+	var lines []string
+	for i := 0; i < len(sans); i++ {
+		if i == 0 && !whiteStarts {
+			// Black's first move is written "1... e5"
+			lines = append(lines, fmt.Sprintf("%d... %s", fullmove, sans[i]))
+			fullmove++
+			continue
+		}
+		line := fmt.Sprintf("%d. %s", fullmove, sans[i])
+		if i+1 < len(sans) {
+			i++
+			line += " " + sans[i]
+		}
+		lines = append(lines, line)
+		fullmove++
+	}
+	return lines
+}
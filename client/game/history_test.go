@@ -0,0 +1,46 @@
+package game
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMoveHistory(t *testing.T) {
+
+	g, err := InitHotseat(HotseatParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bad input and commands are not moves
+	g.in = NewTerminal(strings.NewReader("f2f3\ne7e5\nmoves\ne2e2\ng4\nQh4\nq\n"), os.Stdout)
+	g.PlayHotseat()
+
+	expected := "f3 e5 g4 Qh4#"
+	if sans := strings.Join(g.SANMoves(), " "); sans != expected {
+		t.Error("expected ", expected, " got ", sans)
+	}
+	if moves := g.Moves(); len(moves) != 4 || moves[3].String() != "d8h4" {
+		t.Error("expected Qh4 to be d8h4, got ", moves)
+	}
+	expected = "1. f3 e5 / 2. g4 Qh4#"
+	if list := strings.Join(g.moveList(), " / "); list != expected {
+		t.Error("expected ", expected, " got ", list)
+	}
+}
+
+func TestNumberMoves(t *testing.T) {
+
+	// The lines of each list of moves, numbered from the position's move
+	numbered := map[string][]string{
+		"":                      numberMoves(nil, White, 1),
+		"1. e4":                 numberMoves([]string{"e4"}, White, 1),
+		"1... e5 / 2. Nf3 Nc6":  numberMoves([]string{"e5", "Nf3", "Nc6"}, !White, 1), // Black moves first
+		"40. Kd2 Kd7 / 41. Ke3": numberMoves([]string{"Kd2", "Kd7", "Ke3"}, White, 40),
+	}
+	for expected, lines := range numbered {
+		if got := strings.Join(lines, " / "); got != expected {
+			t.Error("expected ", expected, " got ", got)
+		}
+	}
+}
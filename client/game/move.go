@@ -14,19 +14,31 @@ type move struct {
 	endPiece       rune
 	white          bool
 	brd            board
+	castling       [4]bool
+	castleFiles    [4]int
+	chess960       bool
+	enPassant      [2]int
+	promotion      rune // Piece a pawn becomes on the last rank, 0 otherwise
 }
 
 // #######################################################################
 // (Section 1) Moving and Verifying Moves ################################
 // #######################################################################
 
-func makeMove(b *board, s string, white bool) error {
-	// e.g.	`s := "a2 a3"` OR `s := "a2a3"`
+// Make the move described by s and return it
+func makeMove(p *Position, s string, white bool) (Move, error) {
+	// e.g.	`s := "a2 a3"` OR `s := "a2a3"` OR `s := "e7e8q"` OR SAN, e.g. `s := "Nf3"` OR `s := "O-O"`
 
-	// Parse move string, s, into coordinates
+	// Anything that is not coordinate notation is read as SAN
 	pos := strings.ReplaceAll(s, " ", "")
-	if len(pos) != 4 {
-		return fmt.Errorf("invalid move format")
+	if !coordinatePattern.MatchString(pos) {
+		q := *p
+		q.whiteTurn = white
+		mv, err := parseSAN(&q, pos)
+		if err != nil {
+			return Move{}, err
+		}
+		pos = mv.String()
 	}
 	x1, y1, x2, y2 := int(pos[0]-97), int(8-(pos[1]-48)), int(pos[2]-97), int(8-(pos[3]-48))
 
@@ -34,211 +46,200 @@ func makeMove(b *board, s string, white bool) error {
 
 	// Check that the coordinates are within the bounds of the board
 	if !inBounds(x1, y1) || !inBounds(x2, y2) {
-		return fmt.Errorf("invalid bounds for move")
+		return Move{}, fmt.Errorf("invalid bounds for move")
 	}
 
-	// Create a move struct, validate the move, and then make the move
-	m := move{x1, y1, x2, y2, b[x1][y1], b[x2][y2], white, *b}
-	if ok, err := validateMove(m); ok {
-		b[m.x1][m.y1], b[m.x2][m.y2] = '-', b[m.x1][m.y1]
-	} else {
-		return fmt.Errorf("move is invalid: %w", err)
+	// An optional fifth character chooses the piece to promote to
+	var promotion rune
+	if len(pos) == 5 {
+		promotion = unicode.ToLower(rune(pos[4]))
+		if !strings.ContainsRune("qrbn", promotion) {
+			return Move{}, fmt.Errorf("can only promote to q, r, b, or n")
+		}
+		promotion = colored(promotion, white)
 	}
 
-	return nil
+	// The move has to be one of the legal moves
+	q := *p
+	q.whiteTurn = white
+	mv, err := findMove(&q, Square{x1, y1}, Square{x2, y2}, promotion)
+	if err != nil {
+		return Move{}, fmt.Errorf("move is invalid: %w", err)
+	}
+	p.whiteTurn = white
+	p.Play(mv)
+
+	return mv, nil
 }
 
-// Return true if the move is valid, return false otherwise
-func validateMove(m move) (bool, error) {
+// Return the legal move between two squares, or why there is none
+func findMove(p *Position, from Square, to Square, promotion rune) (Move, error) {
 
-	// Return if start is not my color OR if end is my color
-	validStart := strings.Contains("prnbqkPRNBQK", string(m.startPiece)) && (m.white && unicode.IsUpper(m.startPiece) || !m.white && unicode.IsLower(m.startPiece))
-	validEnd := m.endPiece == '-' || m.white && unicode.IsLower(m.endPiece) || !m.white && unicode.IsUpper(m.endPiece)
-	if !validStart || !validEnd {
-		return false, fmt.Errorf("invalid start or invalid end")
+	bp := newBitPosition(p)
+	var promotes bool
+	for _, m := range bp.legalMoves(nil) {
+		mv := m.toMove()
+		if mv.From != from || mv.To != to {
+			continue
+		}
+		if mv.Promotion == promotion {
+			return mv, nil
+		}
+		promotes = mv.Promotion != 0
 	}
 
-	// Check rules for specific pieces
-	var valid bool
-	var err error
-	switch m.startPiece {
-	case 'P', 'p':
-
-		// Extra rules for pawn
-		if m.y1-m.y2 == 2 && (m.y1 != 1 && m.y1 != 6) {
-			return false, fmt.Errorf("pawn can only advance 2 squares if it has not already moved")
-		} else if (m.x1-m.x2 != 0 && m.endPiece == '-') || (m.x1-m.x2 == 0 && m.endPiece != '-') {
-			return false, fmt.Errorf("pawn can only attack diagonally and move vertically")
-		} else if m.y1-m.y2 >= 1 && !m.white || m.y1-m.y2 <= -1 && m.white {
-			return false, fmt.Errorf("pawn is going the wrong way")
+	// Explain why the move is not legal
+	piece := p.brd[from[0]][from[1]]
+	switch {
+	case piece == '-' || unicode.IsUpper(piece) != p.whiteTurn:
+		return Move{}, fmt.Errorf("there is no piece of yours on %s", from)
+	case promotes && promotion == 0:
+		return Move{}, fmt.Errorf("pawn must be promoted on the last rank, e.g. e7e8q")
+	case promotion != 0:
+		return Move{}, fmt.Errorf("only a pawn reaching the last rank can be promoted")
+	}
+	for _, m := range bp.pseudoLegalMoves(nil) {
+		if mv := m.toMove(); mv.From == from && mv.To == to {
+			return Move{}, fmt.Errorf("cannot put your own king into check")
 		}
+	}
+	return Move{}, fmt.Errorf("%s cannot move there", string(piece))
+}
 
-		valid, err = validateMoveJump(m)
-		// TODO add pawn promotion
-	case 'N', 'n':
-		valid, err = validateMoveJump(m)
-	case 'K', 'k':
-		valid, err = validateMoveJump(m)
-	case 'Q', 'B', 'q', 'b':
-		valid, err = validateMoveCrawl(m)
-	case 'R', 'r':
-		valid, err = validateMoveCrawl(m)
-		// TODO Add castling
-	default:
-		return false, fmt.Errorf("not a valid piece type")
+// Move the pieces of m on the board, including the rook of a castle
+// and the pawn captured en passant
+func applyMove(b *board, m move) {
+	b[m.x1][m.y1], b[m.x2][m.y2] = '-', b[m.x1][m.y1]
+	if m.promotion != 0 {
+		b[m.x2][m.y2] = m.promotion
 	}
-	if !valid {
-		return valid, err
+	if isCastle(m) {
+		// The king and rook land on the same squares as in standard chess, wherever they started
+		right := castleRight(m.white, castleKingside(m))
+		kx, rx := castleDestinations(right)
+		b[m.x1][m.y1], b[m.castleFiles[right]][m.y1] = '-', '-'
+		b[kx][m.y1], b[rx][m.y1] = m.startPiece, colored('r', m.white)
+	} else if isEnPassant(m) {
+		// The captured pawn is beside the start square, not on the end square
+		b[m.x2][m.y1] = '-'
 	}
+}
 
-	// Verify that this move does NOT put our king into check
-	m.brd[m.x1][m.y1], m.brd[m.x2][m.y2] = '-', m.brd[m.x1][m.y1]
-	check, _ := inCheck(m.brd)
-	if unicode.IsUpper(m.startPiece) && check[0] {
-		return !check[0], fmt.Errorf("cannot put your own king into check")
-	} else if unicode.IsLower(m.startPiece) && check[1] {
-		return !check[1], fmt.Errorf("cannot put your own king into check")
+// Update the rest of the position after the pieces of m have been moved
+func updatePosition(p *Position, m move) {
+
+	// Moving a king or rook, or capturing a rook, loses castling rights
+	for i, rx := range p.castleFiles {
+		white := i < castleBK
+		y := homeRank(white)
+		if m.startPiece == colored('k', white) ||
+			m.x1 == rx && m.y1 == y ||
+			m.x2 == rx && m.y2 == y {
+			p.castling[i] = false
+		}
 	}
 
-	return true, nil
-}
+	// A double pawn push leaves a square that can be captured en passant
+	p.enPassant = noSquare
+	isPawn := m.startPiece == 'P' || m.startPiece == 'p'
+	if isPawn && (m.y1-m.y2 == 2 || m.y2-m.y1 == 2) {
+		p.enPassant = [2]int{m.x1, (m.y1 + m.y2) / 2}
+	}
 
-// Pawns, Knights, and Kings jump to a location (as opposed to crawling/sliding)
-func validateMoveJump(m move) (bool, error) {
-	// fmt.Println("coor", m.x1, " ", m.y1, " ", m.x2, " ", m.y2)
-	j := [2]int{m.x1 - m.x2, m.y1 - m.y2}
-	for _, i := range getDirections(m.startPiece) {
-		// fmt.Println("match", i, " ", j)
-		if i == j {
-			return true, nil
-		}
+	// Captures and pawn moves reset the halfmove clock
+	if isPawn || m.endPiece != '-' && !isCastle(m) {
+		p.halfmove = 0
+	} else {
+		p.halfmove++
 	}
-	return false, fmt.Errorf("%s cannot move there", string(m.startPiece))
-}
 
-// Queen, Bishops, and Rooks crawl/slide across the board
-func validateMoveCrawl(m move) (bool, error) {
-	for _, i := range getDirections(m.startPiece) {
-		x, y := m.x1+i[0], m.y1+i[1]
-		for inBounds(x, y) {
-			// fmt.Println(x, y, m.x1, m.y1, m.x2, m.y2, i)
-			if x == m.x2 && y == m.y2 {
-				// We made it to the endPiece
-				return true, nil
-			} else if m.brd[x][y] != '-' {
-				// We ran into another piece
-				break
-			}
-			x += i[0]
-			y += i[1]
-		}
+	if !m.white {
+		p.fullmove++
 	}
-	return false, fmt.Errorf("%s cannot move there", string(m.startPiece))
+	p.whiteTurn = !m.white
 }
 
 // #######################################################################
 // (Section 2) Check and Checkmate #######################################
 // #######################################################################
 
-func inCheck(b board) ([2]bool, error) {
+// Return whether the White and Black kings are in check
+func inCheck(p *Position) ([2]bool, error) {
+	return newBitPosition(p).inCheck()
+}
+
+// Report if the side to move is in check, checkmate, or stalemate
+// Return the outcome of the game, which is Unfinished if play continues
+func reportCheckAndCheckmate(p Position) (Outcome, string, error) {
 
-	// Get location of both kings
-	wk, bk, err := findKings(b)
+	bp := newBitPosition(&p)
+	check, err := bp.inCheck()
 	if err != nil {
-		return [2]bool{false, false}, fmt.Errorf("%w", err)
+		return Outcome{}, "", err
 	}
 
-	// Create moves against the kings
-	mbk := move{x2: bk[0], y2: bk[1], white: true, endPiece: 'k', brd: b}
-	mwk := move{x2: wk[0], y2: wk[1], white: false, endPiece: 'K', brd: b}
-
-	// To determine if a kings is in check,
-	// attempt to validate moves of every pieces against the enemy king
-	var whiteCheck, blackCheck bool
-	for x := 0; x < 8; x++ {
-		for y := 0; y < 8; y++ {
-			if unicode.IsLetter(b[x][y]) {
-				if unicode.IsUpper(b[x][y]) && !blackCheck {
-					mbk.x1, mbk.y1, mbk.startPiece = x, y, b[x][y]
-					blackCheck, _ = validateMove(mbk)
-				} else if unicode.IsLower(b[x][y]) && !whiteCheck {
-					mwk.x1, mwk.y1, mwk.startPiece = x, y, b[x][y]
-					whiteCheck, _ = validateMove(mwk)
-				}
-			}
-		}
+	side, opponent := "White", "Black"
+	checked := check[0]
+	if !p.whiteTurn {
+		side, opponent = opponent, side
+		checked = check[1]
 	}
 
-	return [2]bool{whiteCheck, blackCheck}, nil
+	// Without legal moves the side to move is checkmated or stalemated
+	noMoves := len(bp.legalMoves(nil)) == 0
+	if checked && noMoves {
+		return winFor(!p.whiteTurn, Checkmate), fmt.Sprintf("%s is in checkmate!\n%s wins!", side, opponent), nil
+	} else if noMoves {
+		return Outcome{Draw, Stalemate}, fmt.Sprintf("%s is in stalemate!", side), nil
+	} else if checked {
+		return Outcome{}, fmt.Sprintf("%s is in check!", side), nil
+	}
+	return Outcome{}, "", nil
 }
 
-func inCheckmate(b board, kingcolor bool) bool {
-
-	var m move
-	tmpB := b
+// #######################################################################
+// (Section 3) Helper Functions ##########################################
+// #######################################################################
 
-	for x := 0; x < 8; x++ {
-		for y := 0; y < 8; y++ {
-			if unicode.IsLetter(b[x][y]) &&
-				(kingcolor && unicode.IsUpper(b[x][y]) || !kingcolor && unicode.IsLower(b[x][y])) {
-
-				m = move{x1: x, y1: y, white: kingcolor, startPiece: b[x][y], brd: tmpB}
-				for z := 0; z < 8; z++ {
-					for w := 0; w < 8; w++ {
-						m.x2, m.y2, m.endPiece = z, w, b[z][w]
-						validMove, _ := validateMove(m)
-						if validMove {
-							tmpB[m.x1][m.y1], tmpB[m.x2][m.y2] = '-', tmpB[m.x1][m.y1]
-							inCheck, _ := inCheck(tmpB)
-							if kingcolor && !inCheck[0] {
-								return false
-							} else if !kingcolor && !inCheck[1] {
-								return false
-							} else {
-								// Reset temp board
-								tmpB = b
-							}
-						}
-					}
-				}
-			}
-		}
+// A king moving two squares along its rank is castling,
+// and in Chess960 a king moving onto its own rook is
+func isCastle(m move) bool {
+	if m.startPiece != 'K' && m.startPiece != 'k' || m.y1 != m.y2 {
+		return false
 	}
-
-	return true
+	if m.chess960 {
+		return m.endPiece == colored('r', m.white)
+	}
+	return m.x1-m.x2 == 2 || m.x2-m.x1 == 2
 }
 
-// Prints if any check or checkmate
-// Return true if any checkmate
-func reportCheckAndCheckmate(b board) (bool, error) {
+// A castling king moves, or in Chess960 takes the rook, towards the h-file when castling kingside
+func castleKingside(m move) bool {
+	return m.x2 > m.x1
+}
 
-	check, err := inCheck(b)
-	if err != nil {
-		return false, err
+// Return the files the king and rook end on after castling
+func castleDestinations(right int) (int, int) {
+	if right == castleWQ || right == castleBQ {
+		return 2, 3
 	}
-	if check[0] {
-		if inCheckmate(b, White) {
-			b.printBoard()
-			fmt.Println("White is in checkmate!")
-			fmt.Println("Black wins!")
-			return true, nil
-		}
-		fmt.Println("White is in check!")
-	} else if check[1] {
-		if inCheckmate(b, !White) {
-			b.printBoard()
-			fmt.Println("Black is in checkmate!")
-			fmt.Println("White wins!")
-			return true, nil
-		}
-		fmt.Println("Black is in check!")
-	}
-	return false, nil
+	return 6, 5
 }
 
-// #######################################################################
-// (Section 3) Helper Functions ##########################################
-// #######################################################################
+// A pawn moving diagonally onto the square skipped by a double pawn push
+// is capturing en passant
+func isEnPassant(m move) bool {
+	return (m.startPiece == 'P' || m.startPiece == 'p') && m.endPiece == '-' &&
+		m.x1 != m.x2 && [2]int{m.x2, m.y2} == m.enPassant
+}
+
+// Return the piece in the case of the given color, e.g. 'q' is 'Q' for White
+func colored(piece rune, white bool) rune {
+	if white {
+		return unicode.ToUpper(piece)
+	}
+	return unicode.ToLower(piece)
+}
 
 func inBounds(x int, y int) bool {
 	if x < 8 && y < 8 && x >= 0 && y >= 0 {
@@ -247,21 +248,6 @@ func inBounds(x int, y int) bool {
 	return false
 }
 
-func getDirections(piece rune) [][2]int {
-	var directions = map[rune][][2]int{'P': {{0, 1}, {0, 2}, {1, 1}, {-1, 1}, {0, -1}, {0, -2}, {1, -1}, {-1, -1}},
-		'N': {{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}},
-		'B': {{1, -1}, {-1, 1}, {-1, 0}, {-1, -1}},
-		'R': {{0, 1}, {0, -1}, {1, 0}, {-1, 0}},
-		'Q': {{0, 1}, {0, -1}, {1, 1}, {1, 0}, {1, -1}, {-1, 1}, {-1, 0}, {-1, -1}},
-		'K': {{0, 1}, {0, -1}, {1, 1}, {1, 0}, {1, -1}, {-1, 1}, {-1, 0}, {-1, -1}}}
-
-	if unicode.IsLetter(piece) {
-		return directions[unicode.ToUpper(piece)]
-	} else {
-		return [][2]int{} // TODO Could return error here
-	}
-}
-
 func findKings(b board) ([2]int, [2]int, error) {
 
 	var wk, bk [2]int
@@ -9,13 +9,13 @@ func TestMakeMove(t *testing.T) {
 
 	// Setup
 	var err error
-	b, _ := defaultBoard()
+	p, _ := ParseFEN(StartFEN)
 	// printBoard(b)
 
 	// Series of valid moves
 	validMoves := []string{"a2a3", "e2 e4", "g1 f3", "d1 e2", "e2 b5", "e1d1", "f1 e2", "h1 e1"}
 	for _, x := range validMoves {
-		err = makeMove(b, x, White)
+		_, err = makeMove(p, x, White)
 		if err != nil {
 			t.Error(x, err)
 			break
@@ -26,7 +26,7 @@ func TestMakeMove(t *testing.T) {
 	// Series of invalid moves
 	invalidMoves := []string{"d4d5", "i2a3", "e4e4", "g8h6", "b5e2", "b1a3", "a3a2", "e2 e3", "e1 e3", "d1f1", "f3g4"}
 	for _, x := range invalidMoves {
-		err = makeMove(b, x, White)
+		_, err = makeMove(p, x, White)
 		if err == nil {
 			t.Error(x, " expects error")
 			break
@@ -36,7 +36,7 @@ func TestMakeMove(t *testing.T) {
 	// Test black and pawn movement
 	moves := []string{"f7f5", "f5e4"}
 	for _, x := range moves {
-		err = makeMove(b, x, !White)
+		_, err = makeMove(p, x, !White)
 		if err != nil {
 			t.Error(x, err)
 			break
@@ -58,11 +58,11 @@ func TestInCheck(t *testing.T) {
 		t.Error(err)
 	}
 	// printBoard(b)
-	check, err = inCheck(*b)
+	check, err = inCheck(&Position{brd: *b})
 	if check != [2]bool{true, false} {
 		t.Error(check, " failed inCheck: ", err)
 	}
-	checkmate = inCheckmate(*b, White)
+	checkmate = inCheckmate(Position{brd: *b, enPassant: noSquare}, White)
 	if checkmate != false {
 		t.Error(checkmate, " failed inCheckmate: ")
 	}
@@ -74,11 +74,11 @@ func TestInCheck(t *testing.T) {
 		t.Error(err)
 	}
 	// printBoard(b)
-	check, err = inCheck(*b)
+	check, err = inCheck(&Position{brd: *b})
 	if check != [2]bool{true, false} {
 		t.Error(check, " failed inCheck: ", err)
 	}
-	checkmate = inCheckmate(*b, White)
+	checkmate = inCheckmate(Position{brd: *b, enPassant: noSquare}, White)
 	if checkmate != true {
 		t.Error(checkmate, " failed inCheckmate: ")
 	}
@@ -90,11 +90,11 @@ func TestInCheck(t *testing.T) {
 		t.Error(err)
 	}
 	// printBoard(b)
-	check, err = inCheck(*b)
+	check, err = inCheck(&Position{brd: *b})
 	if check != [2]bool{false, true} {
 		t.Error(check, " failed inCheck: ", err)
 	}
-	checkmate = inCheckmate(*b, !White)
+	checkmate = inCheckmate(Position{brd: *b, enPassant: noSquare}, !White)
 	if checkmate != false {
 		t.Error(checkmate, " failed inCheckmate: ")
 	}
@@ -104,13 +104,13 @@ func TestInCheck(t *testing.T) {
 func TestMoveIntoCheck(t *testing.T) {
 	// Setup
 	var err error
-	b, _ := newBoard("4K3/8/8/8/3q4/8/8/3k4")
+	p, _ := ParseFEN("4K3/8/8/8/3q4/8/8/3k4 w - - 0 1")
 	// printBoard(b)
 
 	// Series of invalid moves
 	invalidMoves := []string{"e8d8", "e8d7", "e8e6"}
 	for _, x := range invalidMoves {
-		err = makeMove(b, x, White)
+		_, err = makeMove(p, x, White)
 		if err == nil {
 			t.Error(x, " expects error")
 			break
@@ -121,7 +121,7 @@ func TestMoveIntoCheck(t *testing.T) {
 	// Series of valid moves
 	validMoves := []string{"e8e7"}
 	for _, x := range validMoves {
-		err = makeMove(b, x, White)
+		_, err = makeMove(p, x, White)
 		if err != nil {
 			t.Error(x, err)
 			break
@@ -131,3 +131,169 @@ func TestMoveIntoCheck(t *testing.T) {
 
 	// printBoard(b)
 }
+
+func TestCastling(t *testing.T) {
+
+	// Castle both ways, with both notations
+	p, _ := ParseFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	moves := []string{"e1g1", "O-O-O"}
+	expected := []string{
+		"r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1",
+		"2kr3r/8/8/8/8/8/8/R4RK1 w - - 2 2",
+	}
+	for i, x := range moves {
+		if _, err := makeMove(p, x, p.WhiteTurn()); err != nil {
+			t.Error(x, err)
+			break
+		}
+		if p.FEN() != expected[i] {
+			t.Error(x, " expected ", expected[i], " got ", p.FEN())
+		}
+	}
+
+	// Series of invalid castles
+	invalid := map[string][]string{
+		"r3k2r/8/8/8/8/8/8/R3K2R w - - 0 1":      {"e1g1", "e1c1", "O-O"},   // No castling rights
+		"r3k2r/8/8/8/8/8/8/RN2K1NR w KQ - 0 1":   {"e1g1", "O-O-O"},         // Pieces in the way
+		"r3k2r/8/8/8/8/8/4r3/R3K2R w KQ - 0 1":   {"e1g1", "e1c1"},          // Out of check
+		"r3kr2/8/8/8/8/8/8/R3K2R w KQ - 0 1":     {"e1g1", "O-O"},           // Through check
+		"r3k1r1/8/8/8/8/8/8/R3K2R w KQ - 0 1":    {"e1g1", "0-0"},           // Into check
+		"4k3/8/8/8/8/8/8/R3K2R b KQ - 0 1":       {"e8g8", "e8c8", "O-O-O"}, // Black has no rooks
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1":   {"e1h1", "e1f1f", "e1b1"}, // Not a castling move
+		"r3k2r/8/8/8/8/8/5b2/R3K2R w KQkq - 0 1": {"e1c1"},                  // Out of check by a bishop
+	}
+	for fen, moves := range invalid {
+		for _, x := range moves {
+			p, _ = ParseFEN(fen)
+			if _, err := makeMove(p, x, p.WhiteTurn()); err == nil {
+				t.Error(fen, " ", x, " expects error")
+			}
+		}
+	}
+
+	// Castling is still possible while the rook, but not the king, is attacked
+	p, _ = ParseFEN("1r2k3/8/8/8/8/8/8/R3K3 w Q - 0 1")
+	if _, err := makeMove(p, "O-O-O", White); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEnPassant(t *testing.T) {
+
+	// White captures en passant right after Black's double pawn push
+	p, _ := ParseFEN("4k3/3p4/8/4P3/8/8/8/4K3 b - - 0 1")
+	moves := []string{"d7d5", "e5d6"}
+	expected := []string{
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2",
+		"4k3/8/3P4/8/8/8/8/4K3 b - - 0 2",
+	}
+	for i, x := range moves {
+		if _, err := makeMove(p, x, p.WhiteTurn()); err != nil {
+			t.Error(x, err)
+			break
+		}
+		if p.FEN() != expected[i] {
+			t.Error(x, " expected ", expected[i], " got ", p.FEN())
+		}
+	}
+
+	// Series of invalid en passant captures
+	invalid := map[string]string{
+		"4k3/8/8/3pP3/8/8/8/4K3 w - - 0 2":   "e5d6", // The double push was not the last move
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2":  "e5f6", // Wrong file
+		"4k3/8/8/3pPp2/8/8/8/4K3 w - f6 0 2": "e5d6", // Only the last pawn pushed can be captured
+		"8/8/8/K2pP2q/8/8/8/4k3 w - d6 0 2":  "e5d6", // Captured pawn was shielding the king
+	}
+	for fen, x := range invalid {
+		p, _ = ParseFEN(fen)
+		if _, err := makeMove(p, x, p.WhiteTurn()); err == nil {
+			t.Error(fen, " ", x, " expects error")
+		}
+	}
+
+	// Capturing en passant is the only way out of check
+	p, _ = ParseFEN("8/3B4/7R/k7/1Pp5/P7/8/7K b - b3 0 1")
+	check, _ := inCheck(p)
+	if !check[1] {
+		t.Error("expected black to be in check")
+	}
+	if inCheckmate(*p, !White) {
+		t.Error("expected black to escape check with c4b3")
+	}
+	p.enPassant = noSquare
+	if !inCheckmate(*p, !White) {
+		t.Error("expected black to be in checkmate without en passant")
+	}
+}
+
+func TestPromotion(t *testing.T) {
+
+	// Promote to a queen, then underpromote to a knight while capturing
+	p, _ := ParseFEN("1r2k3/P7/8/8/8/8/6p1/4K2R w - - 0 1")
+	moves := []string{"a7a8q", "g2h1N"}
+	expected := []string{
+		"Qr2k3/8/8/8/8/8/6p1/4K2R b - - 0 1",
+		"Qr2k3/8/8/8/8/8/8/4K2n w - - 0 2",
+	}
+	for i, x := range moves {
+		if _, err := makeMove(p, x, p.WhiteTurn()); err != nil {
+			t.Error(x, err)
+			break
+		}
+		if p.FEN() != expected[i] {
+			t.Error(x, " expected ", expected[i], " got ", p.FEN())
+		}
+	}
+
+	// Every piece but a king or pawn can be chosen
+	for _, x := range []string{"a7b8q", "a7b8r", "a7b8b", "a7b8n", "a7 b8 Q"} {
+		p, _ = ParseFEN("1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+		if _, err := makeMove(p, x, White); err != nil {
+			t.Error(x, err)
+		}
+	}
+
+	// Series of invalid promotions
+	for _, x := range []string{"a7a8", "a7b8", "a7a8k", "a7a8p", "a7a8x", "e1e2q", "a7a8qq"} {
+		p, _ = ParseFEN("1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+		if _, err := makeMove(p, x, White); err == nil {
+			t.Error(x, " expects error")
+		}
+	}
+	p, _ = ParseFEN("4k3/8/P7/8/8/8/8/4K3 w - - 0 1")
+	if _, err := makeMove(p, "a6a7q", White); err == nil {
+		t.Error("a6a7q expects error")
+	}
+
+	// Promoting is the only way out of check
+	p, _ = ParseFEN("r6K/1P3k2/5n2/8/8/8/8/8 w - - 0 1")
+	if inCheckmate(*p, White) {
+		t.Error("expected white to escape check with b7b8q or b7a8q")
+	}
+}
+
+func TestStalemate(t *testing.T) {
+
+	outcomes := map[string]Outcome{
+		"7k/5Q2/8/8/8/8/8/K7 b - - 0 1":              {Draw, Stalemate},
+		"8/8/8/8/8/1qk5/8/K7 w - - 0 1":              {Draw, Stalemate},
+		"8/8/8/8/8/p7/P1k5/K7 w - - 0 1":             {Draw, Stalemate}, // A blocked pawn cannot move
+		"k7/P7/1K6/8/8/8/8/8 b - - 0 1":              {Draw, Stalemate},
+		"7k/6Q1/6K1/8/8/8/8/8 b - - 0 1":             {WhiteWins, Checkmate},
+		"7k/8/8/8/8/8/8/K5r1 w - - 0 1":              {},
+		"7k/5Q2/8/8/8/8/8/K7 w - - 0 1":              {},
+		"7k/5Q2/8/8/8/8/6p1/K7 b - - 0 1":            {}, // The pawn can still move
+		"rnbqkbnr/pppppppp/8/8/8/8/8/4K3 w kq - 0 1": {},
+	}
+	for fen, expected := range outcomes {
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Error(fen, err)
+			continue
+		}
+		o, _, err := reportCheckAndCheckmate(*p)
+		if err != nil || o != expected {
+			t.Error(fen, " expected ", expected, " got ", o, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"unicode"
+)
+
+// #######################################################################
+// (Section 1) Moves #####################################################
+// #######################################################################
+
+// Square is a file and rank index like the board's, so {0, 0} is a8 and {7, 7} is h1
+type Square [2]int
+
+func (s Square) String() string {
+	return squareName(s[0], s[1])
+}
+
+// MoveFlags mark the special moves
+type MoveFlags uint8
+
+const (
+	DoublePush MoveFlags = 1 << iota
+	EnPassant
+	KingsideCastle
+	QueensideCastle
+)
+
+// Move is a legal move in a position
+type Move struct {
+	From      Square
+	To        Square
+	Piece     rune // Piece being moved, e.g. 'N' for a White knight
+	Capture   rune // Piece being captured, or 0
+	Promotion rune // Piece a pawn is promoted to, or 0
+	Flags     MoveFlags
+}
+
+// String returns the move in the coordinate notation read by the game, e.g. "e7e8q"
+func (m Move) String() string {
+	s := m.From.String() + m.To.String()
+	if m.Promotion != 0 {
+		s += string(unicode.ToLower(m.Promotion))
+	}
+	return s
+}
+
+// #######################################################################
+// (Section 2) Legal Move Generation #####################################
+// #######################################################################
+
+// LegalMoves returns every legal move for the side to move
+func LegalMoves(p *Position) []Move {
+	var moves []Move
+	for _, m := range newBitPosition(p).legalMoves(nil) {
+		moves = append(moves, m.toMove())
+	}
+	return moves
+}
+
+// #######################################################################
+// (Section 3) Playing Moves #############################################
+// #######################################################################
+
+// Create a move for the side to move in the position
+func (p *Position) newMove(x1 int, y1 int, x2 int, y2 int, promotion rune) move {
+	return move{x1, y1, x2, y2, p.brd[x1][y1], p.brd[x2][y2], p.whiteTurn, p.brd, p.castling, p.castleFiles, p.chess960, p.enPassant, promotion}
+}
+
+// Play makes a move returned by LegalMoves
+func (p *Position) Play(mv Move) {
+	m := p.newMove(mv.From[0], mv.From[1], mv.To[0], mv.To[1], mv.Promotion)
+	applyMove(&p.brd, m)
+	updatePosition(p, m)
+}
@@ -0,0 +1,78 @@
+package game
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLegalMoves(t *testing.T) {
+
+	counts := map[string]int{
+		StartFEN: 20,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1": 48,
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1":                            14,
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1":     6,
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8":            44,
+		"7k/5Q2/8/8/8/8/8/K7 b - - 0 1":                                        0,
+	}
+	for fen, expected := range counts {
+		p, err := ParseFEN(fen)
+		if err != nil {
+			t.Error(fen, err)
+			continue
+		}
+		if moves := LegalMoves(p); len(moves) != expected {
+			t.Error(fen, " expected ", expected, " moves, got ", len(moves), moves)
+		}
+	}
+}
+
+func TestLegalMoveDetails(t *testing.T) {
+
+	// Every special move with its flags
+	p, _ := ParseFEN("r3k2r/1P6/8/3pP3/8/8/8/R3K2R w KQkq d6 0 1")
+	expected := map[string]Move{
+		"e1g1":  {Square{4, 7}, Square{6, 7}, 'K', 0, 0, KingsideCastle},
+		"e1c1":  {Square{4, 7}, Square{2, 7}, 'K', 0, 0, QueensideCastle},
+		"e5d6":  {Square{4, 3}, Square{3, 2}, 'P', 'p', 0, EnPassant},
+		"b7a8n": {Square{1, 1}, Square{0, 0}, 'P', 'r', 'N', 0},
+		"b7b8q": {Square{1, 1}, Square{1, 0}, 'P', 0, 'Q', 0},
+		"a1a8":  {Square{0, 7}, Square{0, 0}, 'R', 'r', 0, 0},
+	}
+	found := map[string]Move{}
+	for _, m := range LegalMoves(p) {
+		found[m.String()] = m
+	}
+	for s, m := range expected {
+		if found[s] != m {
+			t.Error(s, " expected ", m, " got ", found[s])
+		}
+	}
+
+	// Four promotions for each pawn move to the last rank
+	var promotions []string
+	for s := range found {
+		if strings.HasPrefix(s, "b7") {
+			promotions = append(promotions, s)
+		}
+	}
+	sort.Strings(promotions)
+	if strings.Join(promotions, " ") != "b7a8b b7a8n b7a8q b7a8r b7b8b b7b8n b7b8q b7b8r" {
+		t.Error("unexpected promotions ", promotions)
+	}
+
+	// A double push is flagged, and the moves play out like makeMove
+	p, _ = ParseFEN(StartFEN)
+	for _, m := range LegalMoves(p) {
+		if m.String() == "e2e4" {
+			if m.Flags != DoublePush {
+				t.Error("expected e2e4 to be a double push")
+			}
+			p.Play(m)
+		}
+	}
+	if p.FEN() != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" {
+		t.Error("unexpected position after e2e4 ", p.FEN())
+	}
+}
@@ -0,0 +1,152 @@
+package game
+
+import "fmt"
+
+// #######################################################################
+// (Section 1) Outcome ###################################################
+// #######################################################################
+
+// Result of a game, from White's point of view
+type Result int
+
+const (
+	Unfinished Result = iota
+	WhiteWins
+	BlackWins
+	Draw
+)
+
+// Reason a game ended
+type Reason string
+
+const (
+	Checkmate            Reason = "checkmate"
+	Resignation          Reason = "resignation"
+	Stalemate            Reason = "stalemate"
+	ThreefoldRepetition  Reason = "threefold repetition"
+	FivefoldRepetition   Reason = "fivefold repetition"
+	FiftyMoveRule        Reason = "fifty-move rule"
+	SeventyFiveMoveRule  Reason = "seventy-five-move rule"
+	InsufficientMaterial Reason = "insufficient material"
+	Timeout              Reason = "timeout"
+	Forfeit              Reason = "forfeit" // The computer opponent failed to make a legal move
+	// A player who runs out of time only draws if their opponent cannot checkmate
+	TimeoutVsInsufficientMaterial Reason = "timeout vs insufficient material"
+)
+
+// Outcome is the result of a game and the reason it ended
+type Outcome struct {
+	Result Result
+	Reason Reason
+}
+
+// Over returns true if the game has ended
+func (o Outcome) Over() bool {
+	return o.Result != Unfinished
+}
+
+// Won returns true if the player of the given color won
+func (o Outcome) Won(white bool) bool {
+	return white && o.Result == WhiteWins || !white && o.Result == BlackWins
+}
+
+// Score returns the result in PGN notation, e.g. "1-0"
+func (o Outcome) Score() string {
+	switch o.Result {
+	case WhiteWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+func (o Outcome) String() string {
+	switch o.Result {
+	case WhiteWins:
+		return fmt.Sprintf("White wins by %s", o.Reason)
+	case BlackWins:
+		return fmt.Sprintf("Black wins by %s", o.Reason)
+	case Draw:
+		return fmt.Sprintf("Draw by %s", o.Reason)
+	}
+	return "Game in progress"
+}
+
+// Return the outcome of a win for the given color
+func winFor(white bool, reason Reason) Outcome {
+	if white {
+		return Outcome{WhiteWins, reason}
+	}
+	return Outcome{BlackWins, reason}
+}
+
+// #######################################################################
+// (Section 2) Repetition and Move Count Draws ###########################
+// #######################################################################
+
+// Record the current position in the repetition table
+func (gs *GameState) recordPosition() {
+	gs.repetitions[gs.pos.hash()]++
+}
+
+// A draw can be claimed once the same position has occurred three times,
+// or after fifty moves by each side without a capture or pawn move
+func (gs *GameState) claimDraw() Outcome {
+	if gs.repetitions[gs.pos.hash()] >= 3 {
+		return Outcome{Draw, ThreefoldRepetition}
+	} else if gs.pos.halfmove >= 100 {
+		return Outcome{Draw, FiftyMoveRule}
+	}
+	return Outcome{}
+}
+
+// The game is drawn without a claim once the same position has occurred five times,
+// after seventy-five moves by each side without a capture or pawn move,
+// or once neither side has the material left to checkmate
+func (gs *GameState) automaticDraw() Outcome {
+	if gs.repetitions[gs.pos.hash()] >= 5 {
+		return Outcome{Draw, FivefoldRepetition}
+	} else if gs.pos.halfmove >= 150 {
+		return Outcome{Draw, SeventyFiveMoveRule}
+	} else if insufficientMaterial(gs.pos.brd) {
+		return Outcome{Draw, InsufficientMaterial}
+	}
+	return Outcome{}
+}
+
+// #######################################################################
+// (Section 3) Dead Positions ############################################
+// #######################################################################
+
+// Return true if no sequence of moves can lead to checkmate:
+// king against king, king and one minor piece against king,
+// or kings and any number of bishops that all stand on the same color of square
+func insufficientMaterial(b board) bool {
+
+	var knights int
+	var bishops [2]int // Bishops on light and dark squares
+
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			switch b[x][y] {
+			case 'K', 'k', '-':
+			case 'N', 'n':
+				knights++
+			case 'B', 'b':
+				bishops[(x+y)%2]++
+			default:
+				// Any pawn, rook, or queen can still deliver checkmate
+				return false
+			}
+		}
+	}
+
+	minors := knights + bishops[0] + bishops[1]
+	if minors <= 1 {
+		return true
+	}
+	return knights == 0 && (bishops[0] == 0 || bishops[1] == 0)
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestInsufficientMaterial(t *testing.T) {
+
+	boards := map[string]bool{
+		"4k3/8/8/8/8/8/8/4K3":      true,  // King against king
+		"4k3/8/8/8/8/8/8/2B1K3":    true,  // King and bishop against king
+		"4k3/8/8/8/8/8/8/1N2K3":    true,  // King and knight against king
+		"2b1k3/8/8/8/8/8/8/2B1K3":  false, // Bishops on opposite colors
+		"3bk3/8/8/8/8/8/8/2B1K3":   true,  // Bishops on the same color
+		"3bk3/8/8/8/8/8/1B6/2B1K3": true,  // Many bishops on the same color
+		"4k3/8/8/8/8/8/8/1NB1K3":   false, // Bishop and knight can checkmate
+		"1n2k3/8/8/8/8/8/8/1N2K3":  false, // Knight against knight can be checkmate
+		"4k3/8/8/8/8/8/8/NN2K3":    false,
+		"4k3/8/8/8/8/8/P7/4K3":     false,
+		"4k3/8/8/8/8/8/8/R3K3":     false,
+		"3qk3/8/8/8/8/8/8/4K3":     false,
+	}
+	for placement, expected := range boards {
+		b, err := newBoard(placement)
+		if err != nil {
+			t.Error(placement, err)
+			continue
+		}
+		if insufficientMaterial(*b) != expected {
+			t.Error(placement, " expected ", expected)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+
+	o := Outcome{BlackWins, Checkmate}
+	if !o.Over() || o.Won(White) || !o.Won(!White) || o.Score() != "0-1" {
+		t.Error("unexpected ", o)
+	}
+	o = Outcome{Draw, InsufficientMaterial}
+	if !o.Over() || o.Won(White) || o.Won(!White) || o.Score() != "1/2-1/2" {
+		t.Error("unexpected ", o)
+	}
+	if o.String() != "Draw by insufficient material" {
+		t.Error("unexpected ", o.String())
+	}
+	o = Outcome{}
+	if o.Over() || o.Score() != "*" {
+		t.Error("unexpected ", o)
+	}
+}
@@ -0,0 +1,32 @@
+package game
+
+import (
+	"sort"
+)
+
+// Perft counts the leaf nodes of the legal move tree to the given depth
+// Comparing the counts with published results verifies the move generator
+func Perft(p *Position, depth int) int {
+	return newBitPosition(p).perft(depth)
+}
+
+// PerftDivide is the perft count below one of the legal moves
+type PerftDivide struct {
+	Move  Move
+	Nodes int
+}
+
+// Divide runs perft below each legal move, sorted by the moves' notation
+// A wrong total can be tracked down by comparing each move against another engine
+func Divide(p *Position, depth int) []PerftDivide {
+	var divides []PerftDivide
+	for _, m := range LegalMoves(p) {
+		next := *p
+		next.Play(m)
+		divides = append(divides, PerftDivide{m, Perft(&next, depth-1)})
+	}
+	sort.Slice(divides, func(i, j int) bool {
+		return divides[i].Move.String() < divides[j].Move.String()
+	})
+	return divides
+}
@@ -0,0 +1,58 @@
+package game
+
+import (
+	"testing"
+)
+
+// Published results from https://www.chessprogramming.org/Perft_Results
+var perftPositions = []struct {
+	name  string
+	fen   string
+	nodes []int // Indexed by depth - 1
+}{
+	{"start", StartFEN, []int{20, 400, 8902, 197281, 4865609}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int{48, 2039, 97862, 4085603}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []int{14, 191, 2812, 43238, 674624}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int{6, 264, 9467, 422333}},
+	{"position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", []int{6, 264, 9467, 422333}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []int{44, 1486, 62379, 2103487}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []int{46, 2079, 89890, 3894594}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, x := range perftPositions {
+		for depth, expected := range x.nodes {
+			depth++
+			// The deepest searches are slow, so skip them with -short
+			if testing.Short() && expected > 100000 {
+				continue
+			}
+			p, err := ParseFEN(x.fen)
+			if err != nil {
+				t.Fatal(x.name, err)
+			}
+			if nodes := Perft(p, depth); nodes != expected {
+				t.Error(x.name, " depth ", depth, " expected ", expected, " got ", nodes)
+			}
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	p, _ := ParseFEN(StartFEN)
+	divides := Divide(p, 3)
+	if len(divides) != 20 {
+		t.Fatal("expected 20 moves, got ", len(divides))
+	}
+	total := 0
+	for _, d := range divides {
+		total += d.Nodes
+	}
+	if total != 8902 {
+		t.Error("expected the divides to add up to 8902, got ", total)
+	}
+	// Sorted by notation
+	if divides[0].Move.String() != "a2a3" || divides[0].Nodes != 380 {
+		t.Error("expected a2a3 380 first, got ", divides[0])
+	}
+}
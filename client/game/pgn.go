@@ -0,0 +1,364 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// #######################################################################
+// (Section 1) PGN Export ################################################
+// #######################################################################
+
+// PGNTags are the Seven Tag Roster, except the Result which comes from the game
+// Empty tags are written as "?", the PGN mark for an unknown value
+type PGNTags struct {
+	Event string
+	Site  string
+	Date  string // e.g. "2022.04.30", today if empty
+	Round string
+	White string
+	Black string
+}
+
+// Fill in the tags left empty
+func (t PGNTags) withDefaults(now time.Time) PGNTags {
+	if t.Date == "" {
+		t.Date = now.Format("2006.01.02")
+	}
+	for _, tag := range []*string{&t.Event, &t.Site, &t.Round, &t.White, &t.Black} {
+		if *tag == "" {
+			*tag = "?"
+		}
+	}
+	return t
+}
+
+// PGN returns the game so far in Portable Game Notation, with the outcome as its result
+func (gs *GameState) PGN(o Outcome) string {
+
+	var sb strings.Builder
+	tag := func(name string, value string) {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `"`, `\"`)
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, value)
+	}
+	tag("Event", gs.tags.Event)
+	tag("Site", gs.tags.Site)
+	tag("Date", gs.tags.Date)
+	tag("Round", gs.tags.Round)
+	tag("White", gs.tags.White)
+	tag("Black", gs.tags.Black)
+	tag("Result", o.Score())
+	if gs.clock != nil {
+		tag("TimeControl", gs.clock.tc.String())
+	}
+	if gs.start.chess960 {
+		tag("Variant", "Chess960")
+	}
+	// A game that did not start from the standard position needs its FEN
+	if fen := gs.start.FEN(); fen != StartFEN {
+		tag("SetUp", "1")
+		tag("FEN", fen)
+	}
+	sb.WriteString("\n")
+
+	tokens := strings.Fields(strings.Join(gs.moveList(), " "))
+	if n, i, ok := gs.outOfBook(); ok {
+		tokens = insertComment(tokens, i, fmt.Sprintf("out of book at move %d", n))
+	}
+	if o.Over() {
+		tokens = append(tokens, "{"+o.String()+"}")
+	}
+	tokens = append(tokens, o.Score())
+	sb.WriteString(wrapTokens(tokens, 79))
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// Insert a comment after the move with the given index, counting from 0, in the tokens of a move list
+func insertComment(tokens []string, index int, comment string) []string {
+	for i, token := range tokens {
+		if strings.HasSuffix(token, ".") {
+			// A move number, e.g. "1." or "1..."
+			continue
+		}
+		if index == 0 {
+			return append(tokens[:i+1], append([]string{"{" + comment + "}"}, tokens[i+1:]...)...)
+		}
+		index--
+	}
+	return tokens
+}
+
+// Join the tokens with spaces, starting a new line before any line would
+// grow longer than width, as PGN import formats expect
+func wrapTokens(tokens []string, width int) string {
+	var sb strings.Builder
+	length := 0
+	for _, token := range tokens {
+		if length > 0 && length+1+len(token) > width {
+			sb.WriteString("\n")
+			length = 0
+		} else if length > 0 {
+			sb.WriteString(" ")
+			length++
+		}
+		sb.WriteString(token)
+		length += len(token)
+	}
+	return sb.String()
+}
+
+// SavePGN writes the game to a new file in the PGN directory and returns its path
+func (gs *GameState) SavePGN(o Outcome) (string, error) {
+
+	if err := os.MkdirAll(gs.pgnDir, 0755); err != nil {
+		return "", fmt.Errorf("cannot create PGN directory: %w", err)
+	}
+
+	// e.g. "20220430-153000-alice-vs-bob.pgn"
+	name := fmt.Sprintf("%s-%s-vs-%s.pgn", time.Now().Format("20060102-150405"),
+		fileSafe(gs.tags.White), fileSafe(gs.tags.Black))
+	path := filepath.Join(gs.pgnDir, name)
+
+	if err := os.WriteFile(path, []byte(gs.PGN(o)), 0644); err != nil {
+		return "", fmt.Errorf("cannot save PGN: %w", err)
+	}
+	return path, nil
+}
+
+// Save the finished game if there is a PGN directory
+func (gs *GameState) saveFinishedGame(o Outcome) {
+	if gs.pgnDir == "" {
+		return
+	}
+	path, err := gs.SavePGN(o)
+	if err != nil {
+		gs.out.Message(fmt.Sprint("Error: ", err))
+		return
+	}
+	gs.out.Message("Game saved to " + path)
+}
+
+// Replace the characters that do not belong in a file name
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// #######################################################################
+// (Section 2) PGN Import ################################################
+// #######################################################################
+
+// PGNGame is a game read from a PGN file, with every move validated
+type PGNGame struct {
+	Tags   map[string]string // Every tag pair, e.g. Tags["White"]
+	Start  Position          // Position the game started from
+	Moves  []Move
+	Result string // e.g. "1-0", or "*" if the game is unfinished
+}
+
+// Tag returns the value of a tag pair, or "?" if the game does not have it
+func (g *PGNGame) Tag(name string) string {
+	if v, ok := g.Tags[name]; ok {
+		return v
+	}
+	return "?"
+}
+
+// PGNError reports the game and move of a PGN that could not be read
+type PGNError struct {
+	Game  int    // Counting from 1
+	Move  int    // Fullmove number, or 0 if the error is not in a move
+	Black bool   // True if the move is Black's
+	SAN   string // The move that could not be read
+	Err   error
+}
+
+func (e *PGNError) Error() string {
+	if e.Move == 0 {
+		return fmt.Sprintf("invalid PGN in game %d: %v", e.Game, e.Err)
+	}
+	dots := "."
+	if e.Black {
+		dots = "..."
+	}
+	return fmt.Sprintf("invalid PGN in game %d at move %d%s %s: %v", e.Game, e.Move, dots, e.SAN, e.Err)
+}
+
+func (e *PGNError) Unwrap() error {
+	return e.Err
+}
+
+// e.g. `[White "Kasparov, Garry"]`
+var tagPattern = regexp.MustCompile(`^\[\s*([A-Za-z0-9_]+)\s+"((?:[^"\\]|\\.)*)"\s*\]$`)
+
+// e.g. "12." or "12...", possibly followed by the move, e.g. "12.Nf3"
+var moveNumberPattern = regexp.MustCompile(`^[0-9]+\.+`)
+
+// ParsePGN reads every game in a PGN file
+// Comments, variations, and annotation glyphs are skipped
+func ParsePGN(r io.Reader) ([]*PGNGame, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read PGN: %w", err)
+	}
+	s := string(data)
+
+	var games []*PGNGame
+	var game *PGNGame  // Game being read, or nil between games
+	var pos *Position  // Position of the game being read, once its moves begin
+	var variations int // Depth of the variation being skipped
+
+	fail := func(err error) error {
+		return &PGNError{Game: len(games), Err: err}
+	}
+	newGame := func() {
+		game = &PGNGame{Tags: make(map[string]string), Result: "*"}
+		games = append(games, game)
+		pos = nil
+	}
+	// The moves begin once the tags that could set up the position are read
+	beginMoves := func() error {
+		if game == nil {
+			newGame()
+		}
+		if pos != nil {
+			return nil
+		}
+		fen := StartFEN
+		if f, ok := game.Tags["FEN"]; ok {
+			fen = f
+		}
+		p, err := ParseFEN(fen)
+		if err != nil {
+			return fail(err)
+		}
+		pos = p
+		game.Start = *p
+		return nil
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		// Escaped lines and comments to the end of the line
+		case c == '%' && (i == 0 || s[i-1] == '\n'), c == ';':
+			i = skipPast(s, i, "\n")
+
+		case c == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				if game == nil {
+					newGame()
+				}
+				return nil, fail(fmt.Errorf("comment is never closed"))
+			}
+			i += end + 1
+
+		case c == '[':
+			// A tag after the moves of a game starts the next game
+			if game == nil || pos != nil {
+				newGame()
+			}
+			end := tagEnd(s, i)
+			if end < 0 {
+				return nil, fail(fmt.Errorf("tag is never closed"))
+			}
+			match := tagPattern.FindStringSubmatch(s[i:end])
+			if match == nil {
+				return nil, fail(fmt.Errorf("cannot read tag %s", s[i:end]))
+			}
+			game.Tags[match[1]] = tagUnescaper.Replace(match[2])
+			i = end
+
+		case c == '(':
+			if err := beginMoves(); err != nil {
+				return nil, err
+			}
+			variations++
+			i++
+
+		case c == ')':
+			if variations == 0 {
+				return nil, fail(fmt.Errorf("variation is closed but never opened"))
+			}
+			variations--
+			i++
+
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\r\n[]{}();", rune(s[end])) {
+				end++
+			}
+			token := s[i:end]
+			i = end
+
+			if err := beginMoves(); err != nil {
+				return nil, err
+			}
+			token = moveNumberPattern.ReplaceAllString(token, "")
+			if token == "" || token[0] == '$' || variations > 0 {
+				continue
+			}
+			switch token {
+			case "1-0", "0-1", "1/2-1/2", "*":
+				game.Result = token
+				game = nil
+				continue
+			}
+
+			m, err := makeMove(pos, token, pos.whiteTurn)
+			if err != nil {
+				return nil, &PGNError{Game: len(games), Move: pos.fullmove, Black: !pos.whiteTurn, SAN: token, Err: err}
+			}
+			game.Moves = append(game.Moves, m)
+		}
+	}
+
+	if variations > 0 {
+		return nil, fail(fmt.Errorf("variation is never closed"))
+	}
+	return games, nil
+}
+
+var tagUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+
+// Return the index just past the "]" closing the tag at i, or -1
+func tagEnd(s string, i int) int {
+	quoted := false
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && quoted:
+			j++
+		case s[j] == '"':
+			quoted = !quoted
+		case s[j] == ']' && !quoted:
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// Return the index just past the next sep, or the end of s
+func skipPast(s string, i int, sep string) int {
+	end := strings.Index(s[i:], sep)
+	if end < 0 {
+		return len(s)
+	}
+	return i + end + len(sep)
+}
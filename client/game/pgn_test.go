@@ -0,0 +1,200 @@
+package game
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPGN(t *testing.T) {
+
+	g, err := InitHotseat(HotseatParams{
+		Tags:   PGNTags{Event: "Test", Date: "2022.04.30", White: "alice", Black: `bob "the rook"`},
+		PGNDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("f3\ne5\ng4\nQh4\n"), os.Stdout)
+	o := g.PlayHotseat()
+
+	want := `[Event "Test"]
+[Site "?"]
+[Date "2022.04.30"]
+[Round "?"]
+[White "alice"]
+[Black "bob \"the rook\""]
+[Result "0-1"]
+
+1. f3 e5 2. g4 Qh4# {Black wins by checkmate} 0-1
+`
+	if pgn := g.PGN(o); pgn != want {
+		t.Error("expected\n", want, "got\n", pgn)
+	}
+
+	// The finished game was saved
+	files, err := filepath.Glob(filepath.Join(g.pgnDir, "*-alice-vs-bob__the_rook_.pgn"))
+	if err != nil || len(files) != 1 {
+		t.Fatal("expected one saved game, got ", files, err)
+	}
+	saved, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != want {
+		t.Error("expected\n", want, "saved\n", string(saved))
+	}
+}
+
+func TestPGNFromFEN(t *testing.T) {
+
+	fen := "4k3/8/8/8/8/8/8/R3K3 b Q - 0 40"
+	g, err := InitHotseat(HotseatParams{FEN: fen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.play("Kd7"); err != nil {
+		t.Fatal(err)
+	}
+	if err = g.play("O-O-O"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A game in progress has no result yet
+	pgn := g.PGN(Outcome{})
+	for _, s := range []string{
+		`[Result "*"]`,
+		`[SetUp "1"]`,
+		`[FEN "` + fen + `"]`,
+		"\n40... Kd7 41. O-O-O+ *\n",
+	} {
+		if !strings.Contains(pgn, s) {
+			t.Error(s, " expected in\n", pgn)
+		}
+	}
+}
+
+func TestPGNTagDefaults(t *testing.T) {
+	tags := PGNTags{White: "alice"}.withDefaults(time.Date(2022, 4, 3, 0, 0, 0, 0, time.UTC))
+	want := PGNTags{Event: "?", Site: "?", Date: "2022.04.03", Round: "?", White: "alice", Black: "?"}
+	if tags != want {
+		t.Error("expected ", want, " got ", tags)
+	}
+}
+
+func TestWrapTokens(t *testing.T) {
+	tokens := strings.Fields(strings.Repeat("1. e4 e5 ", 20))
+	for _, line := range strings.Split(wrapTokens(tokens, 79), "\n") {
+		if len(line) > 79 || strings.HasPrefix(line, " ") || strings.HasSuffix(line, " ") {
+			t.Error("badly wrapped line ", line)
+		}
+	}
+}
+
+func TestParsePGN(t *testing.T) {
+
+	pgn := `[Event "F/S Return Match"]
+[Site "Belgrade, Serbia JUG"]
+[Date "1992.11.04"]
+[Round "29"]
+[White "Fischer, Robert J."]
+[Black "Spassky, Boris V."]
+[Result "1/2-1/2"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 {This opening is called the Ruy Lopez.} 3... a6
+4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7
+11. c4 c6 12. cxb5 axb5 13. Nc3 Bb7 14. Bg5 b4 15. Nb1 h6 16. Bh4 c5 17. dxe5
+Nxe4 18. Bxe7 Qxe7 19. exd6 Qf6 20. Nbd2 Nxd6 21. Nc4 Nxc4 22. Bxc4 Nb6
+23. Ne5 Rae8 24. Bxf7+ Rxf7 25. Nxf7 Rxe1+ 26. Qxe1 Kxf7 27. Qe3 Qg5 28. Qxg5
+hxg5 29. b3 Ke6 30. a3 Kd6 31. axb4 cxb4 32. Ra5 Nd5 33. f3 Bc8 34. Kf2 Bf5
+35. Ra7 g6 36. Ra6+ Kc5 37. Ke1 Nf4 38. g3 Nxh3 39. Kd2 Kb5 40. Rd6 Kc5 41. Ra6
+Nf2 42. g4 Bd3 43. Re6 1/2-1/2
+
+% An escaped line
+[White "a \"quoted\" name"] [Black "b"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/8/R3K3 w Q - 0 1"]
+
+1.O-O-O ; A comment to the end of the line
+1... Ke7 (1... Kf7 2. Rd7+) 2. Rd7+! $1 *
+
+e4 e5 1-0
+`
+	games, err := ParsePGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 3 {
+		t.Fatal("expected 3 games, got ", len(games))
+	}
+
+	g := games[0]
+	if g.Tags["White"] != "Fischer, Robert J." || g.Result != "1/2-1/2" || len(g.Moves) != 85 {
+		t.Error("got ", g.Tags, " ", g.Result, " with ", len(g.Moves), " moves")
+	}
+	final := NewReplay(g).positions[85]
+	if fen := final.FEN(); fen != "8/8/4R1p1/2k3p1/1p4P1/1P1b1P2/3K1n2/8 b - - 2 43" {
+		t.Error("got final position ", fen)
+	}
+
+	g = games[1]
+	if g.Tags["White"] != `a "quoted" name` || g.Tags["Black"] != "b" || g.Result != "*" {
+		t.Error("got ", g.Tags, " ", g.Result)
+	}
+	if len(g.Moves) != 3 || g.Start.FEN() != "4k3/8/8/8/8/8/8/R3K3 w Q - 0 1" {
+		t.Error("got ", g.Moves, " from ", g.Start.FEN())
+	}
+
+	// A game without tags
+	if g = games[2]; len(g.Tags) != 0 || len(g.Moves) != 2 || g.Result != "1-0" {
+		t.Error("got ", g.Tags, " ", g.Moves, " ", g.Result)
+	}
+}
+
+func TestParsePGNErrors(t *testing.T) {
+
+	// Series of invalid PGN, and the start of the error each gives
+	invalid := map[string]string{
+		"1. e4 e5 2. Nf3 Nc6 *\n\n1. e4 e5 2. Ke3 *":      "invalid PGN in game 2 at move 2. Ke3: illegal move Ke3",
+		"[Event \"x\"]\n1. e4 e5 2. Nf3 Nf6 3. Nc3 Nc3 *": "invalid PGN in game 1 at move 3... Nc3: illegal move Nc3",
+		"1. Nf3 Nf6 2. d3 d6 3. Nd2 *":                    "invalid PGN in game 1 at move 3. Nd2: ambiguous move Nd2: it could be from",
+		"1. e4 *\n[Event x]\n":                            "invalid PGN in game 2: cannot read tag [Event x]",
+		"1. e4 {never closed":                             "invalid PGN in game 1: comment is never closed",
+		"1. e4 (1. d4 *":                                  "invalid PGN in game 1: variation is never closed",
+		"[FEN \"8/8/8 w - - 0 1\"]\n1. e4 *":              "invalid PGN in game 1: invalid FEN piece placement",
+	}
+	for x, expected := range invalid {
+		_, err := ParsePGN(strings.NewReader(x))
+		var pgnErr *PGNError
+		if !errors.As(err, &pgnErr) {
+			t.Error(x, " expects a PGNError, got ", err)
+		} else if !strings.HasPrefix(err.Error(), expected) {
+			t.Error(x, " expected ", expected, " got ", err)
+		}
+	}
+}
+
+func TestPGNRoundTrip(t *testing.T) {
+
+	g, err := InitHotseat(HotseatParams{Tags: PGNTags{White: "alice", Black: "bob"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []string{"e4", "d5", "exd5", "Qxd5", "Nc3", "Qa5", "d4", "c6", "Nf3", "Bg4", "Bf4", "e6", "h3", "Bxf3", "Qxf3", "Bb4", "Be2", "Nd7", "a3", "O-O-O"} {
+		if err := g.play(x); err != nil {
+			t.Fatal(x, err)
+		}
+	}
+
+	games, err := ParsePGN(strings.NewReader(g.PGN(Outcome{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 || !reflect.DeepEqual(games[0].Moves, g.Moves()) || games[0].Tags["Black"] != "bob" {
+		t.Error("expected the game to read back, got ", games)
+	}
+}
@@ -0,0 +1,269 @@
+package game
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// #######################################################################
+// (Section 1) Polyglot Books ############################################
+// #######################################################################
+
+var ErrorBadBook = errors.New("not a Polyglot opening book")
+
+const bookEntrySize = 16
+
+// BookEntry is one move of a Polyglot opening book, stored big-endian in 16 bytes
+type BookEntry struct {
+	Key    uint64 // Polyglot key of the position the move is played in
+	Move   uint16 // The move, as decoded by decodeBookMove
+	Weight uint16 // How often the move should be chosen, relative to the others
+	Learn  uint32
+}
+
+// Book is a Polyglot opening book, whose entries are sorted by key
+type Book struct {
+	entries []BookEntry
+}
+
+// OpenBook reads the Polyglot opening book at path, e.g. "performance.bin"
+func OpenBook(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ReadBook(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// ReadBook reads a Polyglot opening book
+func ReadBook(r io.Reader) (*Book, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%bookEntrySize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a whole number of entries", ErrorBadBook, len(data))
+	}
+
+	b := &Book{entries: make([]BookEntry, len(data)/bookEntrySize)}
+	for i := range b.entries {
+		e := data[i*bookEntrySize:]
+		b.entries[i] = BookEntry{
+			Key:    binary.BigEndian.Uint64(e),
+			Move:   binary.BigEndian.Uint16(e[8:]),
+			Weight: binary.BigEndian.Uint16(e[10:]),
+			Learn:  binary.BigEndian.Uint32(e[12:]),
+		}
+		if i > 0 && b.entries[i].Key < b.entries[i-1].Key {
+			return nil, fmt.Errorf("%w: entry %d is out of order", ErrorBadBook, i)
+		}
+	}
+	return b, nil
+}
+
+// Len returns the number of entries in the book
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Entries returns the entries for the position with the Polyglot key
+func (b *Book) Entries(key uint64) []BookEntry {
+	i := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].Key >= key })
+	j := i
+	for j < len(b.entries) && b.entries[j].Key == key {
+		j++
+	}
+	return b.entries[i:j]
+}
+
+// #######################################################################
+// (Section 2) Book Moves ################################################
+// #######################################################################
+
+// BookMove is a legal move found in a book
+type BookMove struct {
+	Move   Move
+	Weight uint16
+}
+
+// Moves returns the legal moves the book has for p, with the most often played first
+func (b *Book) Moves(p *Position) []BookMove {
+	var moves []BookMove
+	legal := LegalMoves(p)
+	for _, e := range b.Entries(p.PolyglotKey()) {
+		if m, ok := decodeBookMove(p, e.Move, legal); ok {
+			moves = append(moves, BookMove{Move: m, Weight: e.Weight})
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Weight > moves[j].Weight })
+	return moves
+}
+
+// Pieces a pawn is promoted to, by the promotion bits of a book move
+const bookPromotions = " nbrq"
+
+// Find the legal move encoded in a book move
+// The bits are the to file and rank, the from file and rank, and the promotion,
+// with rank 0 being rank 1. Castling is encoded as the king taking its own rook
+func decodeBookMove(p *Position, raw uint16, legal []Move) (Move, bool) {
+	to := Square{int(raw & 7), 7 - int(raw>>3&7)}
+	from := Square{int(raw >> 6 & 7), 7 - int(raw>>9&7)}
+	promotion := int(raw >> 12 & 7)
+	if promotion >= len(bookPromotions) {
+		return Move{}, false
+	}
+
+	for _, m := range legal {
+		if m.From != from {
+			continue
+		}
+		if m.Flags&(KingsideCastle|QueensideCastle) != 0 {
+			// The rook taken is on the side the king castles to
+			kingside := m.Flags&KingsideCastle != 0
+			rook := colored('R', m.Piece == 'K')
+			if p.Piece(to) == rook && to[1] == from[1] && kingside == (to[0] > from[0]) {
+				return m, true
+			}
+			continue
+		}
+		if m.To == to && (m.Promotion == 0) == (promotion == 0) &&
+			(promotion == 0 || m.String()[4] == bookPromotions[promotion]) {
+			return m, true
+		}
+	}
+	return Move{}, false
+}
+
+// #######################################################################
+// (Section 3) Polyglot Keys #############################################
+// #######################################################################
+
+// Order of the pieces in polyglotRandom, each black piece before the white one
+const polyglotPieces = "pPnNbBrRqQkK"
+
+// PolyglotKey returns the key of the position in Polyglot opening books
+// Like Hash it is a Zobrist hash, but with the random numbers every Polyglot book uses
+func (p *Position) PolyglotKey() uint64 {
+	var key uint64
+
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if i := strings.IndexRune(polyglotPieces, p.brd[x][y]); i >= 0 {
+				// Polyglot counts ranks up from rank 1, where y counts down from rank 8
+				key ^= polyglotRandom[64*i+8*(7-y)+x]
+			}
+		}
+	}
+	// The castling rights are in the same order as Position.castling
+	for i, ok := range p.castling {
+		if ok {
+			key ^= polyglotRandom[768+i]
+		}
+	}
+	if p.canCaptureEnPassant() {
+		key ^= polyglotRandom[772+p.enPassant[0]]
+	}
+	if p.whiteTurn {
+		key ^= polyglotRandom[780]
+	}
+
+	return key
+}
+
+// #######################################################################
+// (Section 4) Books in Games ############################################
+// #######################################################################
+
+// Print the book moves for the side to move, with how often each is played
+func (gs *GameState) bookHint() {
+	if gs.book == nil {
+		gs.out.Message("There is no opening book, see -book.")
+		return
+	}
+	moves := gs.book.Moves(gs.pos)
+	if len(moves) == 0 {
+		if n, _, ok := gs.outOfBook(); ok {
+			gs.out.Message(fmt.Sprintf("No book moves, the game left the book at move %d.", n))
+		} else {
+			gs.out.Message("No book moves.")
+		}
+		return
+	}
+
+	total := 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+	var hints []string
+	for _, m := range moves {
+		hint := gs.pos.SAN(m.Move)
+		if total > 0 {
+			hint += fmt.Sprintf(" (%d%%)", int(m.Weight)*100/total)
+		}
+		hints = append(hints, hint)
+	}
+	gs.out.Message("Book moves: " + strings.Join(hints, ", "))
+}
+
+// Return a book move for the side to move, chosen at random by weight,
+// or false if there is no book or the position is not in it
+func (gs *GameState) bookMove() (Move, bool) {
+	if gs.book == nil {
+		return Move{}, false
+	}
+	moves := gs.book.Moves(gs.pos)
+	if len(moves) == 0 {
+		return Move{}, false
+	}
+
+	total := 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+	if total == 0 {
+		return moves[0].Move, true
+	}
+	n := rand.New(rand.NewSource(time.Now().UnixNano())).Intn(total)
+	for _, m := range moves {
+		if n < int(m.Weight) {
+			return m.Move, true
+		}
+		n -= int(m.Weight)
+	}
+	return moves[0].Move, true
+}
+
+// Return the number of the move on which the game left the book, and its index in the history,
+// or false if there is no book or every move so far was in it
+func (gs *GameState) outOfBook() (int, int, bool) {
+	if gs.book == nil {
+		return 0, 0, false
+	}
+	p := gs.start
+	for i, u := range gs.history {
+		inBook := false
+		for _, m := range gs.book.Moves(&p) {
+			if m.Move == u.move {
+				inBook = true
+				break
+			}
+		}
+		if !inBook {
+			return p.fullmove, i, true
+		}
+		p.Play(u.move)
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,198 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Encode a book entry, with the squares given like "e2" and "e4"
+func bookEntry(key uint64, from string, to string, promotion int, weight uint16) []byte {
+	square := func(s string) uint16 { return uint16(s[0]-'a') | uint16(s[1]-'1')<<3 }
+	e := make([]byte, bookEntrySize)
+	binary.BigEndian.PutUint64(e, key)
+	binary.BigEndian.PutUint16(e[8:], square(to)|square(from)<<6|uint16(promotion)<<12)
+	binary.BigEndian.PutUint16(e[10:], weight)
+	return e
+}
+
+// Join book entries into a book, sorted by key as a book is
+func sortedBook(entries ...[]byte) []byte {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return binary.BigEndian.Uint64(entries[i]) < binary.BigEndian.Uint64(entries[j])
+	})
+	return bytes.Join(entries, nil)
+}
+
+// Return the Polyglot key of the FEN
+func polyglotKey(fen string) uint64 {
+	p, _ := ParseFEN(fen)
+	return p.PolyglotKey()
+}
+
+func TestPolyglotKey(t *testing.T) {
+
+	// Moves played from the start, then the key the Polyglot specification gives
+	keys := map[string]uint64{
+		"":                                   0x463b96181691fc9c,
+		"e2e4":                               0x823c9b50fd114196,
+		"e2e4 d7d5":                          0x0756b94461c50fb0,
+		"e2e4 d7d5 e4e5":                     0x662fafb965db29d4,
+		"e2e4 d7d5 e4e5 f7f5":                0x22a48b5a8e47ff78, // En passant is possible
+		"e2e4 d7d5 e4e5 f7f5 e1e2":           0x652a607ca3f242c1,
+		"e2e4 d7d5 e4e5 f7f5 e1e2 e8f7":      0x00fdd303c946bdd9,
+		"a2a4 b7b5 h2h4 b5b4 c2c4":           0x3c8123ea7b067637,
+		"a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a3": 0x5c3f9b829b279560,
+	}
+	for moves, expected := range keys {
+		p, _ := ParseFEN(StartFEN)
+		for _, x := range strings.Fields(moves) {
+			if _, err := makeMove(p, x, p.WhiteTurn()); err != nil {
+				t.Fatal(moves, err)
+			}
+		}
+		if key := p.PolyglotKey(); key != expected {
+			t.Error(moves, " expected ", fmt.Sprintf("%016x", expected), " got ", fmt.Sprintf("%016x", key))
+		}
+	}
+
+	// The en passant square only counts if a pawn can capture on it
+	if polyglotKey("4k3/8/8/8/4P3/8/8/4K3 b - e3 0 1") != polyglotKey("4k3/8/8/8/4P3/8/8/4K3 b - - 0 1") {
+		t.Error("expected an en passant square no pawn can capture on to be ignored")
+	}
+}
+
+func TestReadBook(t *testing.T) {
+
+	start := polyglotKey(StartFEN)
+	castle := polyglotKey("4k3/8/8/8/8/8/8/4K2R w K - 0 1")
+	promote := polyglotKey("k7/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	data := sortedBook(
+		bookEntry(promote, "b7", "b8", 2, 1),
+		bookEntry(castle, "e1", "h1", 0, 1),
+		bookEntry(castle, "e1", "f1", 0, 2),
+		bookEntry(start, "e2", "e4", 0, 10),
+		bookEntry(start, "d2", "d4", 0, 30),
+		bookEntry(start, "e2", "e5", 0, 50), // Not legal, so left out
+	)
+	b, err := ReadBook(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 6 || len(b.Entries(start)) != 3 || len(b.Entries(start+1)) != 0 {
+		t.Error("expected 6 entries, 3 for the start position")
+	}
+
+	// Position, then its book moves with the most often played first
+	// Castling is the king taking its own rook, while the king moving beside it is not
+	moves := map[string]string{
+		StartFEN:                         "d2d4 e2e4",
+		"4k3/8/8/8/8/8/8/4K2R w K - 0 1": "e1f1 e1g1",
+		"k7/1P6/8/8/8/8/8/4K3 w - - 0 1": "b7b8b",
+		"4k3/8/8/8/8/8/8/4K3 w - - 0 1":  "",
+	}
+	for fen, expected := range moves {
+		p, _ := ParseFEN(fen)
+		var got []string
+		for _, m := range b.Moves(p) {
+			got = append(got, m.Move.String())
+		}
+		if strings.Join(got, " ") != expected {
+			t.Error(fen, " expected ", expected, " got ", got)
+		}
+	}
+
+	// Chess960 castling is also the king taking its rook
+	p, _ := ParseFEN("1k6/8/8/8/8/8/8/RK6 w A - 0 1")
+	b, _ = ReadBook(bytes.NewReader(bookEntry(p.PolyglotKey(), "b1", "a1", 0, 1)))
+	if moves := b.Moves(p); len(moves) != 1 || moves[0].Move.Flags&QueensideCastle == 0 {
+		t.Error("expected queenside castling, got ", moves)
+	}
+
+	// Series of books that cannot be read
+	bad := [][]byte{
+		data[:20],
+		bytes.Join([][]byte{bookEntry(2, "e2", "e4", 0, 1), bookEntry(1, "d2", "d4", 0, 1)}, nil),
+	}
+	for _, x := range bad {
+		if _, err := ReadBook(bytes.NewReader(x)); !errors.Is(err, ErrorBadBook) {
+			t.Error(len(x), " bytes expected ErrorBadBook got ", err)
+		}
+	}
+}
+
+// A book of the Ruy Lopez, with 1. e4 played three times as often as 1. d4
+// Without d4 the book has one move for each position, so the computer's choice is known
+func testBook(t *testing.T, d4 bool) *Book {
+	type entry struct {
+		fen      string
+		from, to string
+		weight   uint16
+	}
+	book := []entry{
+		{StartFEN, "e2", "e4", 3},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "e7", "e5", 1},
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", "g1", "f3", 1},
+	}
+	if d4 {
+		book = append(book, entry{StartFEN, "d2", "d4", 1})
+	}
+	var entries [][]byte
+	for _, e := range book {
+		entries = append(entries, bookEntry(polyglotKey(e.fen), e.from, e.to, 0, e.weight))
+	}
+	b, err := ReadBook(bytes.NewReader(sortedBook(entries...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBookHint(t *testing.T) {
+
+	b := testBook(t, true)
+	moves := scriptedMoves{"book", "e4", "book", "c5", "book", "q"}
+	r := &recorder{}
+	g, err := InitHotseat(HotseatParams{Book: b, Input: &moves, Output: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.PlayHotseat()
+
+	found := map[string]bool{}
+	for _, m := range r.messages {
+		found[m] = true
+	}
+	for _, expected := range []string{
+		"Book moves: e4 (75%), d4 (25%)",
+		"Book moves: e5 (100%)",
+		"No book moves, the game left the book at move 1.",
+	} {
+		if !found[expected] {
+			t.Error("expected the message ", expected, " got ", r.messages)
+		}
+	}
+}
+
+func TestBookAI(t *testing.T) {
+
+	// The computer plays from the book until it is out of it
+	moves := scriptedMoves{"e5", "a6", "q"}
+	g, err := InitAI(AIParams{YouStart: false, Depth: 1, Book: testBook(t, false), Input: &moves, Output: &recorder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.PlayAI()
+	if sans := g.SANMoves(); len(sans) < 4 || sans[0] != "e4" || sans[2] != "Nf3" {
+		t.Error("expected the computer to play e4 and Nf3 from the book, got ", sans)
+	}
+
+	// The PGN notes the first move that was not in the book
+	if pgn := g.PGN(Outcome{}); !strings.Contains(pgn, "2. Nf3 a6 {out of book at move 2}") {
+		t.Error("expected the game to leave the book at move 2, got ", pgn)
+	}
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// #######################################################################
+// (Section 1) Replay ####################################################
+// #######################################################################
+
+// Replay steps forwards and backwards through a game read from a PGN
+type Replay struct {
+	Game      *PGNGame
+	positions []Position // Position before each move, then the final position
+	sans      []string
+	ply       int // Number of moves played
+}
+
+func NewReplay(g *PGNGame) *Replay {
+	r := &Replay{Game: g, positions: []Position{g.Start}}
+	p := g.Start
+	for _, m := range g.Moves {
+		r.sans = append(r.sans, p.SAN(m))
+		p.Play(m)
+		r.positions = append(r.positions, p)
+	}
+	return r
+}
+
+// Position returns the position after the moves played so far
+func (r *Replay) Position() Position {
+	return r.positions[r.ply]
+}
+
+// Forward plays the next move, returning false at the end of the game
+func (r *Replay) Forward() bool {
+	if r.ply == len(r.Game.Moves) {
+		return false
+	}
+	r.ply++
+	return true
+}
+
+// Back takes back the last move, returning false at the start of the game
+func (r *Replay) Back() bool {
+	if r.ply == 0 {
+		return false
+	}
+	r.ply--
+	return true
+}
+
+// Describe the last move played, e.g. "14... Nxe5 (28 of 60)"
+func (r *Replay) status() string {
+	if r.ply == 0 {
+		return fmt.Sprintf("Start of the game (0 of %d)", len(r.sans))
+	}
+	p := r.positions[r.ply-1]
+	dots := "."
+	if !p.whiteTurn {
+		dots = "..."
+	}
+	return fmt.Sprintf("%d%s %s (%d of %d)", p.fullmove, dots, r.sans[r.ply-1], r.ply, len(r.sans))
+}
+
+// #######################################################################
+// (Section 2) Replay Loop ###############################################
+// #######################################################################
+
+// Play steps through the game with commands typed at the terminal, until "q" or the input ends
+func (r *Replay) Play(t *Terminal) {
+
+	t.Message(fmt.Sprintf("----- %s vs %s, %s -----", r.Game.Tag("White"), r.Game.Tag("Black"), r.Game.Result))
+	t.Message("Press enter or type \"n\" for the next move, \"b\" to go back,")
+	t.Message("\"s\" or \"e\" to go to the start or end, and \"q\" to quit.")
+	r.print(t)
+
+	for {
+		t.prompt("Replay: ")
+		input, err := t.readLine(nil)
+		t.answered()
+		// The last line can end without a newline
+		if err != nil && input == "" {
+			return
+		}
+		switch strings.TrimSpace(input) {
+		case "", "n", "next":
+			if !r.Forward() {
+				t.Message("This is the end of the game: " + r.Game.Result)
+				continue
+			}
+		case "b", "back":
+			if !r.Back() {
+				t.Message("This is the start of the game.")
+				continue
+			}
+		case "s", "start":
+			r.ply = 0
+		case "e", "end":
+			r.ply = len(r.Game.Moves)
+		case "fen":
+			pos := r.Position()
+			t.Message(pos.FEN())
+			continue
+		case "q", "quit":
+			return
+		default:
+			t.Message("Unknown command, try n, b, s, e, fen, or q.")
+			continue
+		}
+		r.print(t)
+	}
+}
+
+// Show the board from White's side with the last move, then the move it was
+func (r *Replay) print(t *Terminal) {
+	v := BoardView{Position: r.Position(), White: true}
+	if r.ply > 0 {
+		last := r.Game.Moves[r.ply-1]
+		v.LastMove = &last
+	}
+	v.Check = checkedKing(&v.Position)
+	t.Board(v)
+	t.Message(r.status())
+}
@@ -0,0 +1,58 @@
+package game
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+
+	games, err := ParsePGN(strings.NewReader("1. f3 e5 2. g4 Qh4# 0-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReplay(games[0])
+
+	if r.Back() {
+		t.Error("expected no move to take back at the start")
+	}
+	for i := 0; i < 4; i++ {
+		if !r.Forward() {
+			t.Fatal("expected move ", i+1)
+		}
+	}
+	if r.Forward() {
+		t.Error("expected no move after the end")
+	}
+	if s := r.status(); s != "2... Qh4# (4 of 4)" {
+		t.Error("got status ", s)
+	}
+	pos := r.Position()
+	if fen := pos.FEN(); fen != "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3" {
+		t.Error("got ", fen)
+	}
+
+	r.Back()
+	r.Back()
+	if s := r.status(); s != "1... e5 (2 of 4)" {
+		t.Error("got status ", s)
+	}
+
+	// Commands step through the game until "q"
+	r.Play(NewTerminal(strings.NewReader("e\nb\nx\nn\nb\nq\nn\n"), os.Stdout))
+	if s := r.status(); s != "2. g4 (3 of 4)" {
+		t.Error("got status ", s)
+	}
+	var out bytes.Buffer
+	r.Play(NewTerminal(strings.NewReader("s"), &out))
+	if s := r.status(); s != "Start of the game (0 of 4)" {
+		t.Error("got status ", s)
+	}
+
+	// The board is drawn by the terminal, in letters when it cannot show Unicode
+	if !strings.Contains(out.String(), "8 | r n b q k b n r |") {
+		t.Error("expected the board in letters, got ", out.String())
+	}
+}
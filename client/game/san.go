@@ -0,0 +1,153 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// #######################################################################
+// (Section 1) Parsing SAN ###############################################
+// #######################################################################
+
+var ErrorIllegalMove = errors.New("illegal move")
+var ErrorAmbiguousMove = errors.New("ambiguous move")
+
+// e.g. "e4", "exd5", "Nbd2", "R1e2", "Qh4xe1", "e8=Q"
+var sanPattern = regexp.MustCompile(`^([NBRQK])?([a-h])?([1-8])?(x)?([a-h][1-8])(=?([NBRQnbrq]))?$`)
+
+// Coordinate notation read by makeMove, e.g. "e2e4" or "e7e8q"
+var coordinatePattern = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][qrbnQRBN]?$`)
+
+// parseSAN finds the legal move described by Standard Algebraic Notation
+func parseSAN(p *Position, san string) (Move, error) {
+
+	// Check, checkmate, and annotations do not change the move
+	s := strings.TrimRight(san, "+#!?")
+
+	var candidates []Move
+	switch s {
+	case "O-O", "0-0":
+		candidates = filterMoves(p, func(m Move) bool { return m.Flags&KingsideCastle != 0 })
+	case "O-O-O", "0-0-0":
+		candidates = filterMoves(p, func(m Move) bool { return m.Flags&QueensideCastle != 0 })
+	default:
+		match := sanPattern.FindStringSubmatch(s)
+		if match == nil {
+			return Move{}, fmt.Errorf("cannot read %q as a move, try e.g. e4, Nf3, exd5, O-O, or e8=Q", san)
+		}
+		piece, fromFile, fromRank, to, promotion := match[1], match[2], match[3], match[5], match[7]
+		if piece == "" {
+			piece = "P"
+			// A pawn only changes file when capturing, and then the file it came from is given
+			if fromFile == "" {
+				fromFile = to[:1]
+			}
+		}
+
+		candidates = filterMoves(p, func(m Move) bool {
+			return string(unicode.ToUpper(m.Piece)) == piece &&
+				m.To.String() == to &&
+				(fromFile == "" || m.From.String()[:1] == fromFile) &&
+				(fromRank == "" || m.From.String()[1:] == fromRank) &&
+				(promotion == "" || unicode.ToUpper(m.Promotion) == unicode.ToUpper(rune(promotion[0])))
+		})
+
+		// Every promotion matches when none is given, but one has to be chosen
+		if promotion == "" && len(candidates) > 0 && candidates[0].Promotion != 0 {
+			return Move{}, fmt.Errorf("%w %s: choose a piece to promote to, e.g. %s=Q", ErrorIllegalMove, san, s)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Move{}, fmt.Errorf("%w %s", ErrorIllegalMove, san)
+	} else if len(candidates) > 1 {
+		var from []string
+		for _, m := range candidates {
+			from = append(from, m.From.String())
+		}
+		return Move{}, fmt.Errorf("%w %s: it could be from %s", ErrorAmbiguousMove, san, strings.Join(from, " or "))
+	}
+	return candidates[0], nil
+}
+
+// Return the legal moves that match
+func filterMoves(p *Position, match func(Move) bool) []Move {
+	var moves []Move
+	for _, m := range LegalMoves(p) {
+		if match(m) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// #######################################################################
+// (Section 2) Writing SAN ###############################################
+// #######################################################################
+
+// SAN returns a legal move in the position in Standard Algebraic Notation, e.g. "Nbd2+"
+func (p *Position) SAN(m Move) string {
+
+	var s string
+	piece := unicode.ToUpper(m.Piece)
+	switch {
+	case m.Flags&KingsideCastle != 0:
+		s = "O-O"
+	case m.Flags&QueensideCastle != 0:
+		s = "O-O-O"
+	case piece == 'P':
+		if m.Capture != 0 {
+			s = m.From.String()[:1] + "x"
+		}
+		s += m.To.String()
+		if m.Promotion != 0 {
+			s += "=" + string(unicode.ToUpper(m.Promotion))
+		}
+	default:
+		s = string(piece) + disambiguation(p, m)
+		if m.Capture != 0 {
+			s += "x"
+		}
+		s += m.To.String()
+	}
+
+	// Mark check and checkmate
+	q := *p
+	q.Play(m)
+	if newBitPosition(&q).kingAttacked(q.whiteTurn) {
+		if len(LegalMoves(&q)) == 0 {
+			return s + "#"
+		}
+		return s + "+"
+	}
+	return s
+}
+
+// Return the file, rank, or square needed to tell the move apart from
+// moves by other pieces of the same kind to the same square
+func disambiguation(p *Position, m Move) string {
+
+	var others, sameFile, sameRank bool
+	for _, o := range LegalMoves(p) {
+		if o.Piece != m.Piece || o.To != m.To || o.From == m.From {
+			continue
+		}
+		others = true
+		sameFile = sameFile || o.From[0] == m.From[0]
+		sameRank = sameRank || o.From[1] == m.From[1]
+	}
+
+	from := m.From.String()
+	switch {
+	case !others:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	}
+	return from
+}
@@ -0,0 +1,129 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSAN(t *testing.T) {
+
+	// SAN, then the position and the move it describes there
+	valid := map[string][2]string{
+		"e4":     {StartFEN, "e2e4"},
+		"Nf3":    {StartFEN, "g1f3"},
+		"Nc3+":   {StartFEN, "b1c3"}, // Check markers are ignored
+		"exd5":   {"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "e4d5"},
+		"exf6":   {"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", "e5f6"}, // En passant
+		"O-O":    {"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1"},
+		"0-0-0":  {"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8"},
+		"Rad1":   {"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1"},  // Disambiguated by file
+		"Rhf1":   {"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "h1f1"},  // Disambiguated by file
+		"R1a3":   {"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", "a1a3"},  // Disambiguated by rank
+		"Qa1b2":  {"4k3/8/8/8/8/8/8/Q1Q1K3 w - - 0 1", "a1b2"}, // Disambiguated by square
+		"b8=Q":   {"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q"},
+		"bxa8N#": {"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8n"},
+		"b8R":    {"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8r"},
+	}
+	for x, expected := range valid {
+		p, err := ParseFEN(expected[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := parseSAN(p, x)
+		if err != nil {
+			t.Error(x, " in ", expected[0], " ", err)
+		} else if m.String() != expected[1] {
+			t.Error(x, " in ", expected[0], " expected ", expected[1], " got ", m)
+		}
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+
+	// Series of SAN that describes no legal move, or more than one
+	illegal := map[string][]string{
+		StartFEN:                          {"e5", "Nd2", "O-O", "exd3"},
+		"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1": {"b8"}, // Promotion piece missing
+	}
+	ambiguous := map[string][]string{
+		"4k3/8/8/8/8/8/4K3/R6R w - - 0 1":  {"Rf1"},
+		"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1":  {"Ra3"},
+		"4k3/8/8/8/8/8/8/Q1Q1K3 w - - 0 1": {"Qb2"},
+	}
+	for want, invalid := range map[error]map[string][]string{ErrorIllegalMove: illegal, ErrorAmbiguousMove: ambiguous} {
+		for fen, moves := range invalid {
+			p, _ := ParseFEN(fen)
+			for _, x := range moves {
+				if _, err := parseSAN(p, x); !errors.Is(err, want) {
+					t.Error(fen, " ", x, " expected ", want, " got ", err)
+				}
+			}
+		}
+	}
+
+	// Series of SAN that cannot be read at all
+	p, _ := ParseFEN(StartFEN)
+	for _, x := range []string{"Ze4", "e9"} {
+		if _, err := parseSAN(p, x); err == nil {
+			t.Error(x, " expects error")
+		}
+	}
+}
+
+func TestMakeMoveSAN(t *testing.T) {
+
+	// SAN and coordinate notation can be mixed in the same game
+	p, _ := ParseFEN(StartFEN)
+	moves := []string{"e4", "e7e5", "Nf3", "Nc6", "Bc4", "g8f6", "O-O"}
+	white := true
+	for _, x := range moves {
+		if _, err := makeMove(p, x, white); err != nil {
+			t.Fatal(x, err)
+		}
+		white = !white
+	}
+
+	want := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 5 4"
+	if p.FEN() != want {
+		t.Error("expected ", want, " got ", p.FEN())
+	}
+}
+
+func TestSAN(t *testing.T) {
+
+	// SAN, then the position and the move in coordinate notation
+	sans := map[string][2]string{
+		"e4":      {StartFEN, "e2e4"},
+		"Nf3":     {StartFEN, "g1f3"},
+		"exd5":    {"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "e4d5"},
+		"exf6":    {"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", "e5f6"},
+		"O-O":     {"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1"},
+		"O-O-O":   {"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8"},
+		"Rad1":    {"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1"},
+		"R1a3":    {"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", "a1a3"},
+		"Qab2":    {"4k3/8/8/8/8/8/8/Q1Q1K3 w - - 0 1", "a1b2"},
+		"Qa1b2":   {"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1b2"},
+		"Qa8+":    {"4k3/8/8/8/8/8/8/Q3K3 w - - 0 1", "a1a8"},
+		"bxa8=Q+": {"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8q"},
+		"Ra8#":    {"7k/8/6K1/8/8/8/8/R7 w - - 0 1", "a1a8"},
+		"Qh4#":    {"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2", "d8h4"},
+	}
+	for expected, x := range sans {
+		p, err := ParseFEN(x[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := *p
+		m, err := makeMove(&q, x[1], p.WhiteTurn())
+		if err != nil {
+			t.Fatal(x[1], err)
+		}
+		if san := p.SAN(m); san != expected {
+			t.Error(x[1], " in ", x[0], " expected ", expected, " got ", san)
+		}
+		// The SAN reads back as the same move
+		if back, err := parseSAN(p, expected); err != nil || back != m {
+			t.Error(expected, " in ", x[0], " reads back as ", back, " ", err)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package game
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// #######################################################################
+// (Section 1) Input and Output ##########################################
+// #######################################################################
+
+var ErrorCanceled = errors.New("move no longer wanted")
+
+// MoveSource supplies the local player's moves and commands, e.g. "e4", "undo", or "q"
+type MoveSource interface {
+	// NextMove blocks until the player enters a move or command,
+	// or returns ErrorCanceled once stop is closed, e.g. when their time is up
+	NextMove(stop <-chan struct{}) (string, error)
+	// AcceptTakeback asks whether to let the opponent take back their last move
+	AcceptTakeback() bool
+}
+
+// Renderer shows the game as it is played
+type Renderer interface {
+	// Board shows the position whenever it changes
+	Board(v BoardView)
+	// Moves shows the moves played so far, when the player asks for them
+	Moves(v BoardView)
+	// Message shows a line of text, e.g. "White's Turn" or why a move is invalid
+	Message(text string)
+	// GameOver shows the outcome of the finished game
+	GameOver(o Outcome)
+}
+
+// BoardView is everything a Renderer is given to draw the game
+type BoardView struct {
+	Position Position
+	White    bool             // The local player's color, White in a hotseat game
+	LastMove *Move            // The move that reached the position, or nil
+	Check    *Square          // The square of the king to move if it is in check, or nil
+	Moves    []string         // Moves played so far, e.g. "1. e4 e5"
+	Timed    bool             // True if the game has a clock
+	Clocks   [2]time.Duration // Time left for White, then Black, in a timed game
+	Delay    time.Duration    // What is left of the delay of the side to move, in a timed game
+}
+
+// ClocksAfter returns the clocks once the side to move has used d more of their turn,
+// with their clock counting down only after what is left of their delay
+func (v BoardView) ClocksAfter(d time.Duration) [2]time.Duration {
+	clocks := v.Clocks
+	if d -= v.Delay; d > 0 {
+		i := clockIndex(v.Position.whiteTurn)
+		if clocks[i] -= d; clocks[i] < 0 {
+			clocks[i] = 0
+		}
+	}
+	return clocks
+}
+
+// Return what a Renderer needs to draw the game now
+func (gs *GameState) view() BoardView {
+	v := BoardView{Position: *gs.pos, White: gs.white, Moves: gs.moveList()}
+	if n := len(gs.history); n > 0 {
+		last := gs.history[n-1].move
+		v.LastMove = &last
+	}
+	v.Check = checkedKing(gs.pos)
+	if gs.clock != nil {
+		v.Timed = true
+		v.Clocks = [2]time.Duration{gs.clock.Remaining(true), gs.clock.Remaining(false)}
+		v.Delay = gs.clock.delayLeft()
+	}
+	return v
+}
+
+// Return the square of the king to move if it is in check, or nil
+func checkedKing(p *Position) *Square {
+	check, err := inCheck(p)
+	if err != nil {
+		return nil
+	}
+	wk, bk, _ := findKings(p.brd)
+	if p.whiteTurn && check[0] {
+		return &Square{wk[0], wk[1]}
+	} else if !p.whiteTurn && check[1] {
+		return &Square{bk[0], bk[1]}
+	}
+	return nil
+}
+
+// Show the board through the game's renderer
+func (gs *GameState) printBoard() {
+	gs.out.Board(gs.view())
+}
+
+// Default to the terminal for whichever of the input and output is nil
+func withTerminal(in MoveSource, out Renderer) (MoveSource, Renderer) {
+	t := NewTerminal(os.Stdin, os.Stdout)
+	if in == nil {
+		in = t
+	}
+	if out == nil {
+		out = t
+	}
+	return in, out
+}
+
+// #######################################################################
+// (Section 2) Terminal ##################################################
+// #######################################################################
+
+// Terminal reads moves typed at a prompt and prints the game as text
+type Terminal struct {
+	reader    *bufio.Reader
+	typed     chan line // Lines read from reader, closed after the first error
+	readErr   error     // Why reading stopped, once typed is closed
+	startRead sync.Once
+	out       io.Writer
+	mu        sync.Mutex // Guards out and prompting, as input is read in the background
+	prompting bool       // A prompt is waiting for input, so output must start a new line
+	fancy     bool       // Draw the board with Unicode pieces on colored squares
+}
+
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{reader: bufio.NewReader(in), out: out, fancy: fancyTerminal(out)}
+}
+
+// Return whether out is a terminal that shows both Unicode and colors
+func fancyTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	if info, err := f.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// See https://no-color.org
+	if term := os.Getenv("TERM"); term == "" || term == "dumb" || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	// The first locale variable that is set decides the character set
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := strings.ToUpper(os.Getenv(name)); locale != "" {
+			return strings.Contains(locale, "UTF-8") || strings.Contains(locale, "UTF8")
+		}
+	}
+	return false
+}
+
+// line is a line of input, or the error that ended it
+type line struct {
+	text string
+	err  error
+}
+
+// Read the next line, or return ErrorCanceled once stop is closed
+// Only one goroutine ever reads from the input, so a line is never lost
+// to a read that was given up on, and goes to whoever asks for input next
+func (t *Terminal) readLine(stop <-chan struct{}) (string, error) {
+	t.startRead.Do(func() {
+		t.typed = make(chan line)
+		go func() {
+			for {
+				// ReadString will block until the delimiter is entered
+				text, err := t.reader.ReadString('\n')
+				t.typed <- line{text, err}
+				if err != nil {
+					t.readErr = err
+					close(t.typed)
+					return
+				}
+			}
+		}()
+	})
+	select {
+	case l, ok := <-t.typed:
+		if !ok {
+			return "", t.readErr
+		}
+		return l.text, l.err
+	case <-stop:
+		return "", ErrorCanceled
+	}
+}
+
+func (t *Terminal) NextMove(stop <-chan struct{}) (string, error) {
+	t.prompt("Enter move: ")
+	input, err := t.readLine(stop)
+	t.answered()
+	if errors.Is(err, ErrorCanceled) {
+		return "", err
+	}
+	if err != nil {
+		t.Message(fmt.Sprint("An error occured while reading input. Please try again ", err))
+		return input, fmt.Errorf("cannot read move: %w", err)
+	}
+
+	// Remove the delimeter from the string
+	input = strings.TrimSuffix(input, "\n")
+	input = strings.TrimSuffix(input, "\r")
+	return input, nil
+}
+
+func (t *Terminal) AcceptTakeback() bool {
+	for {
+		t.prompt("Your opponent asks to take back their last move. Accept? (y/n): ")
+		input, err := t.readLine(nil)
+		t.answered()
+		switch strings.ToLower(strings.TrimSpace(input)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		if err != nil {
+			// With no way to answer, the request is declined
+			return false
+		}
+	}
+}
+
+func (t *Terminal) prompt(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprint(t.out, s)
+	t.prompting = true
+}
+
+func (t *Terminal) answered() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prompting = false
+}
+
+// Print the board, with the clocks beside it in a timed game
+func (t *Terminal) Board(v BoardView) {
+	if !v.Timed {
+		t.lines(t.boardLines(v))
+		return
+	}
+	// Your opponent's clock is beside the top rank, and yours beside the bottom one
+	clocks := make([]string, 9)
+	clocks[1] = "Black " + FormatClock(v.Clocks[1])
+	clocks[8] = "White " + FormatClock(v.Clocks[0])
+	if !v.White {
+		clocks[1], clocks[8] = clocks[8], clocks[1]
+	}
+	t.lines(columns(t.boardLines(v), clocks))
+}
+
+// Print the board with the moves beside it
+func (t *Terminal) Moves(v BoardView) {
+	moves := v.Moves
+	if len(moves) == 0 {
+		moves = []string{"No moves yet"}
+	}
+	t.lines(columns(t.boardLines(v), moves))
+}
+
+func (t *Terminal) boardLines(v BoardView) []string {
+	if !t.fancy {
+		return v.Position.brd.basicLines(v.White)
+	}
+	return coloredLines(v)
+}
+
+func (t *Terminal) Message(text string) {
+	t.lines([]string{text})
+}
+
+func (t *Terminal) GameOver(o Outcome) {
+	t.lines([]string{"Game End", o.String()})
+}
+
+// Print the lines, after ending the line of a prompt still waiting for input
+func (t *Terminal) lines(lines []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.prompting {
+		fmt.Fprintln(t.out)
+		t.prompting = false
+	}
+	for _, line := range lines {
+		fmt.Fprintln(t.out, line)
+	}
+}
+
+// #######################################################################
+// (Section 3) Colored Board #############################################
+// #######################################################################
+
+// ANSI escape codes, with 256 color backgrounds for the squares
+const (
+	LightSquare = "\x1b[48;5;187m"
+	DarkSquare  = "\x1b[48;5;137m"
+	LightMoved  = "\x1b[48;5;186m" // Light squares the last move was from or to
+	DarkMoved   = "\x1b[48;5;143m"
+	CheckSquare = "\x1b[48;5;167m" // The square of a king in check
+	PieceColor  = "\x1b[38;5;16m"  // Black, as the White pieces are outlined
+	ResetColor  = "\x1b[0m"
+)
+
+// Return the board as Unicode pieces on colored squares, drawn from the local player's side
+func coloredLines(v BoardView) []string {
+	files := ""
+	for i := 0; i < 8; i++ {
+		files += fmt.Sprintf(" %c ", 'a'+orient(i, v.White))
+	}
+	lines := []string{"  " + files}
+	for i := 0; i < 8; i++ {
+		y := orient(i, v.White)
+		line := fmt.Sprint(8-y, " ")
+		for j := 0; j < 8; j++ {
+			x := orient(j, v.White)
+			line += squareColor(v, x, y) + PieceColor + " " + coloredSymbol(v.Position.brd[x][y]) + " "
+		}
+		lines = append(lines, line+ResetColor+fmt.Sprint(" ", 8-y))
+	}
+	return append(lines, "  "+files)
+}
+
+// Return the background of a square, highlighting the last move and a king in check
+func squareColor(v BoardView, x int, y int) string {
+	sq := Square{x, y}
+	light := (x+y)%2 == 0
+	switch {
+	case v.Check != nil && *v.Check == sq:
+		return CheckSquare
+	case v.LastMove != nil && (v.LastMove.From == sq || v.LastMove.To == sq) && light:
+		return LightMoved
+	case v.LastMove != nil && (v.LastMove.From == sq || v.LastMove.To == sq):
+		return DarkMoved
+	case light:
+		return LightSquare
+	}
+	return DarkSquare
+}
+
+// Return the symbol of a piece, or a space for an empty square
+func coloredSymbol(piece rune) string {
+	if piece == '-' {
+		return " "
+	}
+	return pieceSymbols[piece]
+}
@@ -0,0 +1,166 @@
+package game
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// scriptedMoves supplies moves from a list, and says yes to every takeback
+type scriptedMoves []string
+
+func (s *scriptedMoves) NextMove(stop <-chan struct{}) (string, error) {
+	if len(*s) == 0 {
+		return "", errors.New("no more moves")
+	}
+	move := (*s)[0]
+	*s = (*s)[1:]
+	return move, nil
+}
+
+func (s *scriptedMoves) AcceptTakeback() bool {
+	return true
+}
+
+// recorder keeps everything it is asked to show
+type recorder struct {
+	boards   []BoardView
+	messages []string
+	outcome  Outcome
+}
+
+func (r *recorder) Board(v BoardView)   { r.boards = append(r.boards, v) }
+func (r *recorder) Moves(v BoardView)   { r.messages = append(r.messages, strings.Join(v.Moves, "\n")) }
+func (r *recorder) Message(text string) { r.messages = append(r.messages, text) }
+func (r *recorder) GameOver(o Outcome)  { r.outcome = o }
+
+func TestRenderer(t *testing.T) {
+
+	moves := scriptedMoves{"f3", "e5", "moves", "g4", "Qh4"}
+	r := &recorder{}
+	g, err := InitHotseat(HotseatParams{Input: &moves, Output: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := g.PlayHotseat(); o != (Outcome{BlackWins, Checkmate}) || r.outcome != o {
+		t.Fatal("expected black to win by checkmate, got ", o, r.outcome)
+	}
+
+	// The board is shown at the start and after each move
+	if len(r.boards) != 5 {
+		t.Fatal("expected 5 boards, got ", len(r.boards))
+	}
+	if r.boards[0].LastMove != nil {
+		t.Error("expected no last move at the start")
+	}
+	last := r.boards[4]
+	if last.LastMove == nil || last.LastMove.String() != "d8h4" || last.Position != *g.pos {
+		t.Error("expected the final position after d8h4, got ", last.LastMove, last.Position.FEN())
+	}
+	if strings.Join(last.Moves, " ") != "1. f3 e5 2. g4 Qh4#" {
+		t.Error("got moves ", last.Moves)
+	}
+
+	found := map[string]bool{}
+	for _, m := range r.messages {
+		found[m] = true
+	}
+	for _, want := range []string{"White's Turn", "1. f3 e5", "White is in checkmate!\nBlack wins!"} {
+		if !found[want] {
+			t.Errorf("expected the message %q, got %q", want, r.messages)
+		}
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader("e4\r\n"), &out)
+
+	if move, err := term.NextMove(nil); err != nil || move != "e4" {
+		t.Error("expected e4, got ", move, err)
+	}
+	if _, err := term.NextMove(nil); err == nil {
+		t.Error("expected an error at the end of the input")
+	}
+
+	// A line typed after a move is given up on goes to the next move
+	r, w := io.Pipe()
+	term = NewTerminal(r, &out)
+	stop := make(chan struct{})
+	close(stop)
+	if _, err := term.NextMove(stop); !errors.Is(err, ErrorCanceled) {
+		t.Error("expected ErrorCanceled, got ", err)
+	}
+	go w.Write([]byte("e4\n"))
+	if move, err := term.NextMove(nil); err != nil || move != "e4" {
+		t.Error("expected e4, got ", move, err)
+	}
+
+	// Output after an unanswered prompt starts on a new line
+	out.Reset()
+	term.prompt("Enter move: ")
+	term.Message("Your time is up!")
+	if got := out.String(); got != "Enter move: \nYour time is up!\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestBoardOrientation(t *testing.T) {
+	b, _ := defaultBoard()
+
+	white := b.basicLines(true)
+	if white[0] != "   _A_B_C_D_E_F_G_H_" || white[1] != "8 | r n b q k b n r |" {
+		t.Error("got ", white[:2])
+	}
+	// Black sees their own pieces at the bottom, with the files reversed
+	black := b.basicLines(false)
+	if black[0] != "   _H_G_F_E_D_C_B_A_" || black[1] != "1 | R N B K Q B N R |" || black[8] != "8 | r n b k q b n r |" {
+		t.Error("got ", black)
+	}
+}
+
+func TestColoredBoard(t *testing.T) {
+
+	// Fool's mate, so the last move is d8h4 and White's king is in check
+	g, err := InitP2P(P2PParams{YouStart: false, Output: &recorder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range []string{"f3", "e5", "g4", "Qh4"} {
+		if err = g.play(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v := g.view()
+	if v.Check == nil || *v.Check != (Square{4, 7}) {
+		t.Fatal("expected White's king on e1 to be in check, got ", v.Check)
+	}
+
+	lines := coloredLines(v)
+	if len(lines) != 10 || VisibleLength(lines[0]) != VisibleLength(lines[1])-2 {
+		t.Fatal("got ", lines)
+	}
+	// Drawn from Black's side, so rank 1 is at the top with the h-file on the left
+	if !strings.HasPrefix(lines[0], "   h ") || !strings.HasPrefix(lines[1], "1 ") {
+		t.Error("expected the board from Black's side, got ", lines[:2])
+	}
+	// e1 is the fourth square from the left, and h4 the first
+	squares := strings.Split(lines[1], PieceColor)
+	if !strings.HasSuffix(squares[3], CheckSquare) {
+		t.Errorf("expected the king in check to be highlighted, got %q", squares[3])
+	}
+	squares = strings.Split(lines[4], PieceColor)
+	if !strings.HasSuffix(squares[0], DarkMoved) || !strings.Contains(squares[1], pieceSymbols['q']) {
+		t.Errorf("expected the queen's move to be highlighted, got %q", lines[4])
+	}
+
+	// Output that is not a terminal gets the basic board
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader(""), &out)
+	term.Board(v)
+	if !strings.HasPrefix(out.String(), "   _H_G_F") || strings.Contains(out.String(), "\x1b") {
+		t.Error("expected the basic board, got ", out.String())
+	}
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"time"
+	"unicode"
+)
+
+// #######################################################################
+// (Section 1) Unmaking Moves ############################################
+// #######################################################################
+
+// undo holds a move and the state it destroys, which is all that is needed to take it back
+type undo struct {
+	move      Move
+	castling  [4]bool
+	enPassant [2]int
+	halfmove  int
+	clocks    [2]time.Duration // Time left for White and Black before the move, in a timed game
+}
+
+// Save the state of the position that the next move will destroy
+func newUndo(p *Position) undo {
+	return undo{castling: p.castling, enPassant: p.enPassant, halfmove: p.halfmove}
+}
+
+// Take back the last move made in the position
+func unmakeMove(p *Position, u undo) {
+
+	m := u.move
+	b := &p.brd
+
+	if m.Flags&(KingsideCastle|QueensideCastle) != 0 {
+		// The king and rook go back to wherever they started
+		right := castleRight(unicode.IsUpper(m.Piece), m.Flags&KingsideCastle != 0)
+		kx, rx := castleDestinations(right)
+		y := m.From[1]
+		b[kx][y], b[rx][y] = '-', '-'
+		b[m.From[0]][y], b[p.castleFiles[right]][y] = m.Piece, colored('r', unicode.IsUpper(m.Piece))
+	} else {
+		// A promoted piece goes back to being the pawn it was
+		b[m.From[0]][m.From[1]], b[m.To[0]][m.To[1]] = m.Piece, '-'
+		switch {
+		case m.Flags&EnPassant != 0:
+			// The captured pawn was beside the start square, not on the end square
+			b[m.To[0]][m.From[1]] = m.Capture
+		case m.Capture != 0:
+			b[m.To[0]][m.To[1]] = m.Capture
+		}
+	}
+
+	p.castling, p.enPassant, p.halfmove = u.castling, u.enPassant, u.halfmove
+	p.whiteTurn = unicode.IsUpper(m.Piece)
+	if !p.whiteTurn {
+		p.fullmove--
+	}
+}
+
+// #######################################################################
+// (Section 2) Undo and Redo #############################################
+// #######################################################################
+
+// Take back the last move, returning false if no move has been played
+func (gs *GameState) undo() bool {
+	if len(gs.history) == 0 {
+		return false
+	}
+	// The position is no longer reached, so it no longer counts towards a repetition
+	gs.repetitions[gs.pos.hash()]--
+
+	u := gs.history[len(gs.history)-1]
+	gs.history = gs.history[:len(gs.history)-1]
+	unmakeMove(gs.pos, u)
+	gs.redos = append(gs.redos, u.move)
+	// The clocks go back to how they were before the move
+	if gs.clock != nil {
+		gs.clock.Reset(u.clocks, gs.pos.whiteTurn)
+	}
+	return true
+}
+
+// Play the last move taken back again, returning false if there is none
+func (gs *GameState) redo() bool {
+	if len(gs.redos) == 0 {
+		return false
+	}
+	m := gs.redos[len(gs.redos)-1]
+	gs.redos = gs.redos[:len(gs.redos)-1]
+
+	u := newUndo(gs.pos)
+	u.move = m
+	if gs.clock != nil {
+		u.clocks = gs.clock.remaining
+	}
+	gs.pos.Play(m)
+	gs.history = append(gs.history, u)
+	gs.recordPosition()
+	return true
+}
+
+// #######################################################################
+// (Section 3) P2P Takebacks #############################################
+// #######################################################################
+
+// Messages sent to the peer in place of a move
+const (
+	takebackRequest = "takeback"
+	takebackAccept  = "accept"
+	takebackDecline = "decline"
+)
+
+// A takeback undoes your last move and their reply, so it is your turn again
+func (gs *GameState) canTakeBack() bool {
+	return len(gs.history) >= 2
+}
+
+// Undo your last move and their reply
+func (gs *GameState) takeBack() {
+	gs.undo()
+	gs.undo()
+	// Moves taken back by agreement are not played again
+	gs.redos = nil
+}
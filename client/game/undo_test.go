@@ -0,0 +1,140 @@
+package game
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUnmakeMove(t *testing.T) {
+
+	// Every legal move, two moves deep, is taken back to the exact same position
+	var unmakeAll func(p *Position, depth int)
+	unmakeAll = func(p *Position, depth int) {
+		for _, m := range LegalMoves(p) {
+			before := *p
+			u := newUndo(p)
+			u.move = m
+			p.Play(m)
+			if depth > 1 {
+				unmakeAll(p, depth-1)
+			}
+			unmakeMove(p, u)
+			if *p != before {
+				t.Fatalf("%s in %s: unmade to %s", m, before.FEN(), p.FEN())
+			}
+		}
+	}
+	for _, x := range perftPositions {
+		p, err := ParseFEN(x.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unmakeAll(p, 2)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+
+	g, err := InitHotseat(HotseatParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A misclick is undone, played again, undone again, and replaced
+	g.in = NewTerminal(strings.NewReader("redo\nundo\nf2f3\ne7e5\ng2g4\nundo\nredo\nundo\ng2g3\nredo\nq\n"), os.Stdout)
+	g.PlayHotseat()
+
+	want := []string{"f3", "e5", "g3"}
+	if sans := strings.Join(g.SANMoves(), " "); sans != strings.Join(want, " ") {
+		t.Errorf("got %s, want %s", sans, want)
+	}
+	if g.FEN() != "rnbqkbnr/pppp1ppp/8/4p3/8/5PP1/PPPPP2P/RNBQKBNR b KQkq - 0 2" {
+		t.Error("unexpected position ", g.FEN())
+	}
+
+	// Undone positions do not count towards a repetition
+	g, err = InitHotseat(HotseatParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("g1f3\nundo\ng1f3\nundo\ng1f3\nundo\ng1f3\nundo\ng1f3\nq\n"), os.Stdout)
+	if o := g.PlayHotseat(); o != (Outcome{WhiteWins, Resignation}) {
+		t.Error("expected black to resign, got ", o)
+	}
+}
+
+func TestP2pTakeback(t *testing.T) {
+
+	for _, accept := range []bool{true, false} {
+		rch, wch := make(chan string, 1), make(chan string, 1)
+		g, err := InitP2P(P2PParams{
+			YouStart:  true,
+			ReadChan:  rch,
+			WriteChan: wch})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A takeback needs a move of yours to take back
+		g.in = NewTerminal(strings.NewReader("undo\ne2e4\nundo\nq\n"), os.Stdout)
+
+		answer := takebackDecline
+		if accept {
+			answer = takebackAccept
+		}
+		done := make(chan bool)
+		go func() {
+			<-wch // e2e4
+			rch <- "e7e5"
+			if move := <-wch; move != takebackRequest {
+				t.Error("expected a takeback request, got ", move)
+			}
+			rch <- answer
+			<-wch // q
+			close(done)
+		}()
+
+		g.PlayP2P()
+		<-done
+
+		want := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"
+		if accept {
+			want = StartFEN
+		}
+		if g.FEN() != want {
+			t.Errorf("accept %v: got %s, want %s", accept, g.FEN(), want)
+		}
+	}
+
+	// The opponent asks for a takeback, which is accepted
+	rch, wch := make(chan string, 1), make(chan string, 1)
+	g, err := InitP2P(P2PParams{
+		YouStart:  false,
+		ReadChan:  rch,
+		WriteChan: wch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.in = NewTerminal(strings.NewReader("e7e5\ny\nc7c5\nq\n"), os.Stdout)
+
+	done := make(chan bool)
+	go func() {
+		rch <- "e2e4"
+		<-wch // e7e5
+		rch <- takebackRequest
+		if answer := <-wch; answer != takebackAccept {
+			t.Error("expected the takeback to be accepted, got ", answer)
+		}
+		rch <- "d2d4"
+		<-wch // c7c5
+		rch <- "q"
+		close(done)
+	}()
+
+	if o := g.PlayP2P(); o != (Outcome{BlackWins, Resignation}) {
+		t.Error("expected white to resign, got ", o)
+	}
+	<-done
+	if sans := strings.Join(g.SANMoves(), " "); sans != "d4 c5" {
+		t.Error("got moves ", sans)
+	}
+}
@@ -0,0 +1,89 @@
+package game
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Random keys for Zobrist hashing, XORed together for every feature of a position
+// The seed is fixed so that every client hashes positions the same way
+var zobrist = newZobristKeys(rand.New(rand.NewSource(1912)))
+
+type zobristKeys struct {
+	pieces    [12][8][8]uint64 // Indexed like zobristPieces, then by x and y
+	black     uint64           // Black to move
+	castling  [4]uint64        // Indexed like Position.castling
+	enPassant [8]uint64        // File of the en passant target square
+}
+
+// Order of the pieces in zobristKeys.pieces
+const zobristPieces = "PNBRQKpnbrqk"
+
+func newZobristKeys(r *rand.Rand) *zobristKeys {
+	z := &zobristKeys{}
+	for i := range z.pieces {
+		for x := 0; x < 8; x++ {
+			for y := 0; y < 8; y++ {
+				z.pieces[i][x][y] = r.Uint64()
+			}
+		}
+	}
+	z.black = r.Uint64()
+	for i := range z.castling {
+		z.castling[i] = r.Uint64()
+	}
+	for i := range z.enPassant {
+		z.enPassant[i] = r.Uint64()
+	}
+	return z
+}
+
+// Hash returns the Zobrist hash of the position, which is the same for positions
+// that count as the same for repetition
+func (p *Position) Hash() uint64 {
+	return p.hash()
+}
+
+// hash returns the Zobrist hash of the position
+// Positions that count as the same for repetition share a hash,
+// so the move clocks are ignored
+func (p *Position) hash() uint64 {
+	var h uint64
+
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if i := strings.IndexRune(zobristPieces, p.brd[x][y]); i >= 0 {
+				h ^= zobrist.pieces[i][x][y]
+			}
+		}
+	}
+	if !p.whiteTurn {
+		h ^= zobrist.black
+	}
+	for i, ok := range p.castling {
+		if ok {
+			h ^= zobrist.castling[i]
+		}
+	}
+	if p.canCaptureEnPassant() {
+		h ^= zobrist.enPassant[p.enPassant[0]]
+	}
+
+	return h
+}
+
+// canCaptureEnPassant returns true if a pawn of the side to move stands
+// beside the pawn that just made a double push
+// Otherwise the en passant square makes no difference to the position
+func (p *Position) canCaptureEnPassant() bool {
+	if p.enPassant == noSquare {
+		return false
+	}
+	// The pawn that can capture is on the same rank as the pawn that was pushed
+	x, y := p.enPassant[0], p.enPassant[1]+1
+	if !p.whiteTurn {
+		y = p.enPassant[1] - 1
+	}
+	pawn := colored('p', p.whiteTurn)
+	return inBounds(x-1, y) && p.brd[x-1][y] == pawn || inBounds(x+1, y) && p.brd[x+1][y] == pawn
+}
@@ -0,0 +1,53 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+
+	// Transpositions reach the same position and share a hash
+	p1, _ := ParseFEN(StartFEN)
+	p2, _ := ParseFEN(StartFEN)
+	for _, x := range []string{"g1f3", "g8f6", "b1c3"} {
+		makeMove(p1, x, p1.WhiteTurn())
+	}
+	for _, x := range []string{"b1c3", "g8f6", "g1f3"} {
+		makeMove(p2, x, p2.WhiteTurn())
+	}
+	if p1.hash() != p2.hash() {
+		t.Error("expected transpositions to share a hash")
+	}
+
+	// The move clocks do not change the hash
+	p1, _ = ParseFEN("4k3/8/8/8/8/8/8/4K2R w K - 0 1")
+	p2, _ = ParseFEN("4k3/8/8/8/8/8/8/4K2R w K - 12 40")
+	if p1.hash() != p2.hash() {
+		t.Error("expected move clocks to be ignored")
+	}
+
+	// Side to move, castling rights, and a capturable en passant square all change the hash
+	different := []string{
+		"4k3/8/8/8/8/8/8/4K2R b K - 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w - - 0 1",
+		"4k3/8/8/8/8/8/8/4KR2 w - - 0 1",
+	}
+	for _, fen := range different {
+		p2, _ = ParseFEN(fen)
+		if p1.hash() == p2.hash() {
+			t.Error(fen, " expected a different hash")
+		}
+	}
+
+	// An en passant square only matters if the capture could be made
+	p1, _ = ParseFEN("4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2")
+	p2, _ = ParseFEN("4k3/8/8/3pP3/8/8/8/4K3 w - - 0 2")
+	if p1.hash() == p2.hash() {
+		t.Error("expected a capturable en passant square to change the hash")
+	}
+	p1, _ = ParseFEN("4k3/8/8/3p4/8/8/8/4K3 w - d6 0 2")
+	p2, _ = ParseFEN("4k3/8/8/3p4/8/8/8/4K3 w - - 0 2")
+	if p1.hash() != p2.hash() {
+		t.Error("expected an uncapturable en passant square to be ignored")
+	}
+}
@@ -8,26 +8,171 @@ import (
 	"github.com/jkunzler0/chess/client/game"
 	"github.com/jkunzler0/chess/client/p2p"
 	"github.com/jkunzler0/chess/client/report"
+	"github.com/jkunzler0/chess/client/tui"
+	"github.com/jkunzler0/chess/client/uci"
+	"github.com/jkunzler0/chess/client/web"
 )
 
 func main() {
 	var err error
+
+	// Subcommands have their own flags
+	if len(os.Args) > 1 && os.Args[1] == "perft" {
+		if err = runPerft(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err = runReplay(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "analyse" {
+		if err = runAnalyse(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "uci" {
+		if err = uci.NewEngine(os.Stdout).Run(os.Stdin); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	help := flag.Bool("help", false, "Display Help")
 	cfg := parseFlags()
 
 	if *help {
 		fmt.Printf("Chess!\nUsage:\nRun './chess' for local hotseat game\nor\nRun './chess -p2p' to connect to and play against a local peer\n")
+		fmt.Printf("Run './chess -ai' to play against the computer, with -color black to play Black\n")
+		fmt.Printf("Set its strength with -ai-depth <N> and -ai-time <duration>, e.g. '-ai-depth 4 -ai-time 0' or '-ai-time 5s'\n")
+		fmt.Printf("Run './chess -tc 5+3' to play with 5 minutes each and a 3 second increment, or '-tc 5d3' for a 3 second delay\n")
+		fmt.Printf("Run './chess -fen \"<FEN>\"' to start from a custom position\n")
+		fmt.Printf("Run './chess -variant chess960' for a random Chess960 position, or '-variant chess960:N' for position N from 0 to 959\n")
+		fmt.Printf("Run './chess perft -fen \"<FEN>\" -depth <N>' to count the legal moves N moves deep\n")
+		fmt.Printf("Run './chess replay -file <PGN> -game <N>' to step through a saved game\n")
+		fmt.Printf("Run './chess analyse -file <PGN> -game <N> -engine <path>' to have a UCI engine judge every move of a saved game\n")
+		fmt.Printf("Run './chess -ai -engine <path>' to play against a UCI engine, or './chess -engine <path>' for its hints in a hotseat game\n")
+		fmt.Printf("Run './chess uci' to use the computer as a UCI engine in a chess GUI\n")
+		fmt.Printf("Run './chess -tui' for a full-screen board, where pieces are moved with the arrow keys and enter, or the mouse\n")
+		fmt.Printf("Run './chess -web :8000' to play in a browser at http://localhost:8000\n")
 		fmt.Printf("Game Instructions:\nType moves using the notation, L#L#, in which L is a letter and # is a number.")
-		fmt.Println("Type \"q\" or \"quit\" to quit.")
+		fmt.Println("Standard algebraic notation also works, e.g. \"e4\", \"Nf3\", \"exd5\", \"Nbd2\", or \"e8=Q\".")
+		fmt.Println("Promote a pawn by adding the piece to the move, e.g. \"e7e8q\" or \"e7e8n\".")
+		fmt.Println("Castle by moving the king two squares, or type \"O-O\" or \"O-O-O\".")
+		fmt.Println("In Chess960, castle by moving the king onto its rook, e.g. \"b1a1\", or type \"O-O\" or \"O-O-O\".")
+		fmt.Println("Type \"draw\" to claim a draw by threefold repetition or the fifty-move rule.")
+		fmt.Println("Type \"undo\" or \"redo\" to take back or replay a move. Over P2P, \"undo\" asks your opponent for a takeback.")
+		fmt.Println("Type \"moves\" or \"history\" to print the moves played so far.")
+		fmt.Println("Type \"pgn\" to print the game so far as PGN. Finished games are saved to the -pgn directory.")
+		fmt.Println("Type \"hint\" for the engine's suggested move, except over P2P.")
+		fmt.Println("Type \"book\" for the moves of the -book opening book, except over P2P.")
+		fmt.Println("Type \"fen\" to print the current position as a FEN.")
+		fmt.Println("Type \"q\" or \"quit\" to resign.")
 		os.Exit(0)
 	}
 
+	// A variant chooses the starting position in place of -fen
+	if cfg.variant != "" {
+		if cfg.fen != "" {
+			fmt.Println("Choose either -fen or -variant")
+			os.Exit(1)
+		}
+		if cfg.fen, err = game.ParseVariant(cfg.variant); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// The opening book, for book moves, the computer's openings, and the PGN
+	var book *game.Book
+	if cfg.book != "" {
+		if book, err = game.OpenBook(cfg.book); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
 	var g *game.GameState
+	var tc game.TimeControl
 
-	// If p2p is off, start a hotseat game
+	// If p2p is off, start a hotseat game or a game against the computer
 	if !cfg.p2p {
+		var engine *uci.Client
+		if cfg.engine != "" {
+			if engine, err = uci.Start(cfg.engine); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			defer engine.Close()
+		}
+		// A nil *uci.Client must not become a non-nil game.Analyser
+		var analyser game.Analyser
+		if engine != nil {
+			analyser = engine
+		}
+		if cfg.tc != "" {
+			if tc, err = game.ParseTimeControl(cfg.tc); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		input, output, ui, err := startUI(cfg)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if ui != nil {
+			defer ui.Close()
+		}
+		if cfg.ai {
+			if cfg.color != "white" && cfg.color != "black" {
+				fmt.Println("-color must be white or black")
+				os.Exit(1)
+			}
+			tags := game.PGNTags{Event: "Game against the computer", White: cfg.nickname, Black: "Computer"}
+			if engine != nil {
+				tags.Black = engine.Name
+			}
+			if cfg.color == "black" {
+				tags.White, tags.Black = tags.Black, tags.White
+			}
+			g, err = game.InitAI(game.AIParams{
+				YouStart:    cfg.color == "white",
+				FEN:         cfg.fen,
+				Tags:        tags,
+				PGNDir:      cfg.pgnDir,
+				TimeControl: tc,
+				Depth:       cfg.aiDepth,
+				MoveTime:    cfg.aiTime,
+				Engine:      analyser,
+				Book:        book,
+				Input:       input,
+				Output:      output})
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			g.PlayAI()
+			return
+		}
 		// Initialize GameState
-		g, err = game.InitHotseat()
+		g, err = game.InitHotseat(game.HotseatParams{
+			FEN:         cfg.fen,
+			Tags:        game.PGNTags{Event: "Hotseat game", White: cfg.nickname, Black: cfg.nickname},
+			PGNDir:      cfg.pgnDir,
+			TimeControl: tc,
+			Analyser:    analyser,
+			Book:        book,
+			Input:       input,
+			Output:      output})
 		if err != nil {
 			panic(err)
 		}
@@ -48,13 +193,33 @@ func main() {
 	// On connection to a peer, we receive the GameHello on ch
 	gh := <-ch
 
-	// Create the GameState with the GameHello's information
-	g, err = game.InitP2P(game.P2PParams{
-		YouStart:  gh.White,
-		ReadChan:  gh.RCh,
-		WriteChan: gh.WCh})
-	if err != nil {
-		panic(err)
+	// White chooses the starting position and time control and sends them to Black
+	fen, tcString := cfg.fen, cfg.tc
+	if gh.White {
+		if fen == "" {
+			fen = game.StartFEN
+		}
+		gh.WCh <- fen
+		// An empty message cannot be sent, so an untimed game is sent as "-"
+		if tcString == "" {
+			gh.WCh <- "-"
+		} else {
+			gh.WCh <- tcString
+		}
+	} else {
+		if fen != "" {
+			fmt.Println("Ignoring -fen and -variant, White chooses the starting position")
+		}
+		if tcString != "" {
+			fmt.Println("Ignoring -tc, White chooses the time control")
+		}
+		fen = <-gh.RCh
+		tcString = <-gh.RCh
+	}
+	if tcString != "" && tcString != "-" {
+		if tc, err = game.ParseTimeControl(tcString); err != nil {
+			panic(err)
+		}
 	}
 
 	// Exchange names with the peer
@@ -62,14 +227,82 @@ func main() {
 	peerNickname := <-gh.RCh
 	fmt.Printf("Connected to %s\n", peerNickname)
 
+	// The group is the closest thing a P2P game has to an event or a site
+	tags := game.PGNTags{
+		Event: "P2P game in group " + cfg.p2pConfig.GroupID,
+		Site:  cfg.p2pConfig.GroupID,
+		White: cfg.nickname,
+		Black: peerNickname,
+	}
+	if !gh.White {
+		tags.White, tags.Black = peerNickname, cfg.nickname
+	}
+
+	input, output, ui, err := startUI(cfg)
+	if err != nil {
+		panic(err)
+	}
+	if ui != nil {
+		ui.SetPeer(peerNickname)
+	}
+
+	// Create the GameState with the GameHello's information
+	g, err = game.InitP2P(game.P2PParams{
+		YouStart:    gh.White,
+		FEN:         fen,
+		Tags:        tags,
+		PGNDir:      cfg.pgnDir,
+		TimeControl: tc,
+		Book:        book,
+		Input:       input,
+		Output:      output,
+		ReadChan:    gh.RCh,
+		WriteChan:   gh.WCh})
+	if err != nil {
+		panic(err)
+	}
+
 	// Start the P2P game
-	complete, win := g.PlayP2P()
-	if !complete {
-		fmt.Println("Game ended in a draw.")
-		return
+	outcome := g.PlayP2P()
+	if ui != nil {
+		ui.Close()
 	}
 
 	// Report the result of the game to the server
-	report.ReportResult(cfg.nickname, peerNickname, win)
+	report.ReportResult(cfg.nickname, peerNickname, gh.White, outcome)
+
+}
 
+// userInterface is a way to play other than typing at a prompt
+type userInterface interface {
+	game.MoveSource
+	game.Renderer
+	SetPeer(name string)
+	Close()
+}
+
+// Start the full-screen or browser interface if one is wanted, and return it as the
+// input and output of the game, which are nil for the terminal
+func startUI(cfg *config) (game.MoveSource, game.Renderer, userInterface, error) {
+	var ui userInterface
+	switch {
+	case cfg.tui && cfg.web != "":
+		return nil, nil, nil, fmt.Errorf("choose either -tui or -web")
+	case cfg.tui:
+		screen, err := tui.Start(os.Stdin, os.Stdout)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ui = screen
+	case cfg.web != "":
+		server, err := web.Start(cfg.web)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fmt.Println("Open", server.URL, "in a browser to play")
+		ui = server
+	default:
+		return nil, nil, nil, nil
+	}
+	return ui, ui, ui, nil
 }
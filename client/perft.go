@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// Run './chess perft -fen <FEN> -depth <N>' to count every legal move sequence
+// of N moves from a position, divided by the first move
+func runPerft(args []string) error {
+	fs := flag.NewFlagSet("perft", flag.ExitOnError)
+	fen := fs.String("fen", game.StartFEN, "FEN of the position to search\n")
+	depth := fs.Int("depth", 1, "Number of moves to search\n")
+	fs.Parse(args)
+
+	if *depth < 1 {
+		return fmt.Errorf("depth must be at least 1")
+	}
+	p, err := game.ParseFEN(*fen)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	nodes := 0
+	for _, d := range game.Divide(p, *depth) {
+		fmt.Printf("%s: %d\n", d.Move, d.Nodes)
+		nodes += d.Nodes
+	}
+	elapsed := time.Since(start)
+
+	fmt.Printf("\nNodes searched: %d\n", nodes)
+	fmt.Printf("Time: %v (%.0f nodes/s)\n", elapsed.Round(time.Millisecond), float64(nodes)/elapsed.Seconds())
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// Run './chess replay -file <PGN> -game <N>' to step through a saved game
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	file := fs.String("file", "", "PGN file to replay\n")
+	n := fs.Int("game", 1, "Number of the game to replay in a file of several games\n")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("choose a PGN file with -file")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	games, err := game.ParsePGN(f)
+	if err != nil {
+		return err
+	}
+	if *n < 1 || *n > len(games) {
+		return fmt.Errorf("%s has %d games, cannot replay game %d", *file, len(games), *n)
+	}
+	if len(games) > 1 {
+		for i, g := range games {
+			fmt.Printf("%d. %s vs %s, %s\n", i+1, g.Tag("White"), g.Tag("Black"), g.Result)
+		}
+	}
+
+	game.NewReplay(games[*n-1]).Play(game.NewTerminal(os.Stdin, os.Stdout))
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/jkunzler0/chess/client/game"
 )
 
 type AuthSuccess struct {
@@ -15,17 +16,25 @@ type Report struct {
 	WinnerID   string
 	LoserID    string
 	ReporterID string
+	Draw       bool   // If true, WinnerID and LoserID are just the two players
+	Reason     string // How the game ended, e.g. "checkmate" or "insufficient material"
 }
 
-func ReportResult(us string, them string, win bool) {
+func ReportResult(us string, them string, white bool, o game.Outcome) {
 
 	fmt.Println("Attempting to report game result...")
 
-	var r Report
-	if win {
-		r = Report{WinnerID: us, LoserID: them, ReporterID: us}
+	r := Report{ReporterID: us, Draw: o.Result == game.Draw, Reason: string(o.Reason)}
+	if r.Draw {
+		// Both players must send the same report, so order the players by name
+		r.WinnerID, r.LoserID = us, them
+		if them < us {
+			r.WinnerID, r.LoserID = them, us
+		}
+	} else if o.Won(white) {
+		r.WinnerID, r.LoserID = us, them
 	} else {
-		r = Report{WinnerID: them, LoserID: us, ReporterID: us}
+		r.WinnerID, r.LoserID = them, us
 	}
 
 	client := resty.New()
@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// #######################################################################
+// (Section 1) Layout ####################################################
+// #######################################################################
+
+// Where the board's top left square is drawn on the screen, counting from 1
+const (
+	boardTop  = 3
+	boardLeft = 3
+)
+
+const paneHeight = 14 // Lines beside and below the board, which takes the first ten
+
+const help = "arrows+enter/mouse: move   u undo   r redo   h hint   b book   d draw   : command   q resign"
+
+// Return the lines of the screen
+func (t *TUI) frame(now time.Time) []string {
+	lines := []string{t.status()}
+	left, right := t.boardLines(), t.panes(now)
+	width := game.VisibleLength(left[1])
+	for i := 0; i < paneHeight; i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		lines = append(lines, strings.TrimRight(l+strings.Repeat(" ", width-game.VisibleLength(l)+4)+r, " "))
+	}
+	lines = append(lines, "")
+	lines = append(lines, t.messages...)
+	for i := len(t.messages); i < maxMessages; i++ {
+		lines = append(lines, "")
+	}
+	if t.prompt != "" {
+		return append(lines, t.prompt)
+	}
+	return append(lines, help)
+}
+
+func (t *TUI) status() string {
+	pos := t.view.Position
+	switch {
+	case t.over:
+		return "Game over"
+	case t.peer != "" && pos.WhiteTurn() != t.view.White:
+		return "Waiting for " + t.peer + "'s move"
+	}
+	s := "Black to move"
+	if pos.WhiteTurn() {
+		s = "White to move"
+	}
+	if t.view.Check != nil {
+		s += ", in check"
+	}
+	return s
+}
+
+// #######################################################################
+// (Section 2) Board #####################################################
+// #######################################################################
+
+// The square of the piece picked to move, beside the game's square colors
+const pickSquare = "\x1b[48;5;110m"
+
+// Return the board drawn from the local player's side, with the cursor, the piece
+// picked to move, and the squares it can move to
+func (t *TUI) boardLines() []string {
+	files := "  "
+	for col := 0; col < 8; col++ {
+		files += fmt.Sprintf(" %c ", 'a'+t.square(col, 0)[0])
+	}
+	lines := []string{files}
+
+	destinations := map[game.Square]bool{}
+	if t.selected != nil {
+		for _, m := range t.legal {
+			if m.From == *t.selected {
+				destinations[m.To] = true
+			}
+		}
+	}
+
+	for row := 0; row < 8; row++ {
+		rank := 8 - t.square(0, row)[1]
+		line := fmt.Sprint(rank, " ")
+		for col := 0; col < 8; col++ {
+			sq := t.square(col, row)
+			symbol := " "
+			if piece := t.view.Position.Piece(sq); piece != 0 {
+				symbol = game.PieceSymbol(piece)
+			} else if destinations[sq] {
+				symbol = "·"
+			}
+			left, right := " ", " "
+			if t.choosing && t.cursor == [2]int{col, row} {
+				left, right = "[", "]"
+			} else if destinations[sq] && symbol != "·" {
+				// A capture
+				left, right = "<", ">"
+			}
+			line += t.squareColor(sq) + game.PieceColor + left + symbol + right
+		}
+		lines = append(lines, line+game.ResetColor+fmt.Sprint(" ", rank))
+	}
+	return append(lines, files)
+}
+
+// Return the background of a square, highlighting the last move and a king in check
+func (t *TUI) squareColor(sq game.Square) string {
+	v := t.view
+	moved := v.LastMove != nil && (v.LastMove.From == sq || v.LastMove.To == sq)
+	light := (sq[0]+sq[1])%2 == 0
+	switch {
+	case t.selected != nil && *t.selected == sq:
+		return pickSquare
+	case v.Check != nil && *v.Check == sq:
+		return game.CheckSquare
+	case moved && light:
+		return game.LightMoved
+	case moved:
+		return game.DarkMoved
+	case light:
+		return game.LightSquare
+	}
+	return game.DarkSquare
+}
+
+// #######################################################################
+// (Section 3) Panes #####################################################
+// #######################################################################
+
+// Return the panes beside the board: the clocks, the opponent, captured pieces, and the moves
+func (t *TUI) panes(now time.Time) []string {
+	var lines []string
+	if t.view.Timed {
+		clocks := t.view.Clocks
+		if !t.over {
+			// The clock of the side to move runs until the next view is given
+			clocks = t.view.ClocksAfter(now.Sub(t.viewAt))
+		}
+		lines = append(lines, "Clock     White "+game.FormatClock(clocks[0])+"   Black "+game.FormatClock(clocks[1]))
+	}
+	if t.peer != "" {
+		lines = append(lines, "Opponent  "+t.peer)
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	white, black := captured(&t.view.Position)
+	lines = append(lines, "White took "+white, "Black took "+black, "", "Moves")
+
+	// The latest moves that fit beside the board
+	moves := t.view.Moves
+	if len(moves) == 0 {
+		moves = []string{"No moves yet"}
+	}
+	fit := paneHeight - len(lines)
+	if fit < 1 {
+		fit = 1
+	}
+	if len(moves) > fit {
+		moves = moves[len(moves)-fit:]
+	}
+	return append(lines, moves...)
+}
+
+// Return the pieces captured by White, then by Black, counted from those missing from
+// the board, where a promoted piece stands for a missing pawn
+func captured(p *game.Position) (string, string) {
+	start := map[rune]int{'q': 1, 'r': 2, 'b': 2, 'n': 2, 'p': 8}
+	count := map[rune]int{}
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if piece := p.Piece(game.Square{x, y}); piece != 0 {
+				count[piece]++
+			}
+		}
+	}
+
+	// The pieces of one color missing from the board, as taken by the other
+	missing := func(white bool) string {
+		var s string
+		pawns := start['p']
+		for _, piece := range "qrbn" {
+			if white {
+				piece = unicode.ToUpper(piece)
+			}
+			n := start[unicode.ToLower(piece)] - count[piece]
+			if n < 0 {
+				pawns += n
+				n = 0
+			}
+			s += strings.Repeat(game.PieceSymbol(piece), n)
+		}
+		pawn := 'p'
+		if white {
+			pawn = 'P'
+		}
+		if n := pawns - count[pawn]; n > 0 {
+			s += strings.Repeat(game.PieceSymbol(pawn), n)
+		}
+		return s
+	}
+	return missing(false), missing(true)
+}
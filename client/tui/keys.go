@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// #######################################################################
+// (Section 1) Keys ######################################################
+// #######################################################################
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyRight
+	keyLeft
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyInterrupt
+	keyMouse
+)
+
+type key struct {
+	code keyCode
+	r    rune // The character typed, for keyRune
+	x, y int  // Column and row of a mouse press, counting from 1 at the top left
+}
+
+// Return the keys read from a terminal in raw mode, with the mouse reported as in xterm's SGR mode
+func parseKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		k, n, ok := parseKey(b)
+		if ok {
+			keys = append(keys, k)
+		}
+		b = b[n:]
+	}
+	return keys
+}
+
+// Return the first key and the number of bytes it takes, with ok false for a key that is ignored
+func parseKey(b []byte) (k key, n int, ok bool) {
+	switch b[0] {
+	case '\r', '\n':
+		return key{code: keyEnter}, 1, true
+	case 0x7f, 0x08:
+		return key{code: keyBackspace}, 1, true
+	case 0x03:
+		return key{code: keyInterrupt}, 1, true
+	case 0x1b:
+		return parseEscape(b)
+	}
+	r, n := utf8.DecodeRune(b)
+	return key{code: keyRune, r: r}, n, r != utf8.RuneError
+}
+
+// e.g. "\x1b[A" for the up arrow, or "\x1b[<0;12;5M" for a left click at column 12 and row 5
+func parseEscape(b []byte) (key, int, bool) {
+	if len(b) < 3 || (b[1] != '[' && b[1] != 'O') {
+		return key{code: keyEscape}, 1, true
+	}
+	if arrow := strings.IndexByte("ABCD", b[2]); arrow >= 0 {
+		return key{code: []keyCode{keyUp, keyDown, keyRight, keyLeft}[arrow]}, 3, true
+	}
+
+	// The sequence ends with its first byte from '@' to '~'
+	end := 2
+	for end < len(b) && (b[end] < '@' || b[end] > '~') {
+		end++
+	}
+	if end == len(b) {
+		return key{}, len(b), false
+	}
+	if b[2] != '<' || b[end] != 'M' {
+		// Other keys, and mouse buttons being released
+		return key{}, end + 1, false
+	}
+	fields := strings.Split(string(b[3:end]), ";")
+	if len(fields) != 3 || fields[0] != "0" {
+		// Only presses of the left button are used
+		return key{}, end + 1, false
+	}
+	x, errX := strconv.Atoi(fields[1])
+	y, errY := strconv.Atoi(fields[2])
+	return key{code: keyMouse, x: x, y: y}, end + 1, errX == nil && errY == nil
+}
+
+// #######################################################################
+// (Section 2) Raw Mode ##################################################
+// #######################################################################
+
+var ErrorNoTerminal = errors.New("the TUI needs a terminal")
+
+// Put the terminal in raw mode, so every key is read as it is pressed and not echoed,
+// and return a function that puts it back as it was
+func rawMode(in *os.File) (func(), error) {
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorNoTerminal, err)
+	}
+	if _, err = stty(in, "raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorNoTerminal, err)
+	}
+	return func() {
+		stty(in, strings.TrimSpace(saved))
+	}, nil
+}
+
+func stty(in *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = in
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,400 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// #######################################################################
+// (Section 1) Full-Screen Interface #####################################
+// #######################################################################
+
+var ErrorClosed = errors.New("the TUI is closed")
+
+const maxMessages = 5 // Messages kept on the screen
+
+// TUI is a full-screen terminal interface, where pieces are moved with the arrow keys or mouse
+// It is both the MoveSource and the Renderer of a game
+type TUI struct {
+	out     io.Writer
+	keys    chan key        // Keys pressed, closed at the end of the input
+	done    chan struct{}   // Closed when the TUI is closed
+	stop    <-chan struct{} // Closed when the move being chosen is no longer wanted, or nil
+	close   sync.Once
+	restore func() // Puts the terminal back as it was
+
+	mu       sync.Mutex // Guards out and everything below, as the clock is drawn in the background
+	view     game.BoardView
+	viewAt   time.Time // When the view was given, so the clock of the side to move can run
+	messages []string
+	peer     string       // The P2P opponent's name, or empty
+	cursor   [2]int       // Column and row of the cursor on the board as drawn
+	selected *game.Square // The piece picked to move, or nil
+	choosing bool         // The player is choosing a move from legal
+	legal    []game.Move
+	prompt   string // A question or command on the bottom line, or empty for the help
+	over     bool
+}
+
+// Start takes over the terminal until Close is called
+func Start(in *os.File, out io.Writer) (*TUI, error) {
+	restore, err := rawMode(in)
+	if err != nil {
+		return nil, err
+	}
+	t := newTUI(out)
+	t.restore = restore
+	// The alternate screen, no cursor, and mouse clicks reported in SGR mode
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l\x1b[?1000h\x1b[?1006h")
+	go t.readKeys(in)
+	go t.tick()
+	return t, nil
+}
+
+func newTUI(out io.Writer) *TUI {
+	return &TUI{
+		out:    out,
+		keys:   make(chan key, 16),
+		done:   make(chan struct{}),
+		cursor: [2]int{4, 6}, // e2 from White's side
+	}
+}
+
+// Close gives the terminal back, after waiting for a key if the game is over so its end can be seen
+func (t *TUI) Close() {
+	t.close.Do(func() {
+		t.mu.Lock()
+		over := t.over
+		t.mu.Unlock()
+		if over {
+			t.ask("Press any key to exit")
+			t.nextKey()
+		}
+		close(t.done)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		fmt.Fprint(t.out, "\x1b[?1006l\x1b[?1000l\x1b[?25h\x1b[?1049l")
+		if t.restore != nil {
+			t.restore()
+		}
+		// The last messages stay on the terminal, e.g. how the game ended
+		for _, m := range t.messages {
+			fmt.Fprintln(t.out, m)
+		}
+	})
+}
+
+// SetPeer shows the name of the opponent in a P2P game
+func (t *TUI) SetPeer(name string) {
+	t.mu.Lock()
+	t.peer = name
+	t.mu.Unlock()
+	t.draw()
+}
+
+func (t *TUI) readKeys(in io.Reader) {
+	defer close(t.keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := in.Read(buf)
+		for _, k := range parseKeys(buf[:n]) {
+			select {
+			case t.keys <- k:
+			case <-t.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Redraw the running clock
+func (t *TUI) tick() {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.mu.Lock()
+			timed := t.view.Timed && !t.over
+			t.mu.Unlock()
+			if timed {
+				t.draw()
+			}
+		case <-t.done:
+			return
+		}
+	}
+}
+
+func (t *TUI) nextKey() (key, error) {
+	select {
+	case k, ok := <-t.keys:
+		if !ok {
+			return key{}, ErrorClosed
+		}
+		return k, nil
+	case <-t.done:
+		return key{}, ErrorClosed
+	case <-t.stop:
+		return key{}, game.ErrorCanceled
+	}
+}
+
+// #######################################################################
+// (Section 2) Choosing Moves ############################################
+// #######################################################################
+
+// Commands given by a single key
+var commandKeys = map[rune]string{'u': "undo", 'r': "redo", 'h': "hint", 'b': "book", 'd': "draw"}
+
+// NextMove waits for a piece to be moved on the board, or a command to be given
+func (t *TUI) NextMove(stop <-chan struct{}) (string, error) {
+	t.stop = stop
+	defer func() { t.stop = nil }()
+	t.mu.Lock()
+	pos := t.view.Position
+	t.legal = game.LegalMoves(&pos)
+	t.choosing, t.selected = true, nil
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.choosing, t.selected, t.legal = false, nil, nil
+		t.mu.Unlock()
+		t.draw()
+	}()
+	t.draw()
+
+	for {
+		k, err := t.nextKey()
+		if err != nil {
+			return "", err
+		}
+		var moves []game.Move
+		switch {
+		case k.code >= keyUp && k.code <= keyLeft:
+			t.moveCursor(k.code)
+		case k.code == keyEnter || k.code == keyRune && k.r == ' ':
+			moves = t.pick(t.cursorSquare())
+		case k.code == keyMouse:
+			if sq, ok := t.squareAt(k.x, k.y); ok {
+				moves = t.pick(sq)
+			}
+		case k.code == keyEscape:
+			t.mu.Lock()
+			t.selected = nil
+			t.mu.Unlock()
+		case k.code == keyInterrupt || k.code == keyRune && k.r == 'q':
+			if t.confirm("Resign? (y/n)") {
+				return "q", nil
+			}
+		case k.code == keyRune && k.r == ':':
+			if command, ok := t.readLine(":"); ok && command != "" {
+				return command, nil
+			}
+		case k.code == keyRune && commandKeys[k.r] != "":
+			return commandKeys[k.r], nil
+		}
+
+		if len(moves) == 1 {
+			return moves[0].String(), nil
+		}
+		if len(moves) > 1 {
+			if move, ok := t.promotion(moves); ok {
+				return move, nil
+			}
+		}
+		t.draw()
+	}
+}
+
+// Move the cursor around the board as it is drawn
+func (t *TUI) moveCursor(code keyCode) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	step := map[keyCode][2]int{keyUp: {0, -1}, keyDown: {0, 1}, keyRight: {1, 0}, keyLeft: {-1, 0}}[code]
+	for i := range t.cursor {
+		t.cursor[i] = (t.cursor[i] + step[i] + 8) % 8
+	}
+}
+
+func (t *TUI) cursorSquare() game.Square {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.square(t.cursor[0], t.cursor[1])
+}
+
+// Return the square drawn in a column and row of the board
+func (t *TUI) square(col int, row int) game.Square {
+	if t.view.White {
+		return game.Square{col, row}
+	}
+	return game.Square{7 - col, 7 - row}
+}
+
+// Return the square clicked at a column and row of the screen, and move the cursor to it
+func (t *TUI) squareAt(x int, y int) (game.Square, bool) {
+	col, row := (x-boardLeft)/3, y-boardTop
+	if x < boardLeft || col > 7 || row < 0 || row > 7 {
+		return game.Square{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cursor = [2]int{col, row}
+	return t.square(col, row), true
+}
+
+// Pick a square, returning the moves to it from the selected piece, of which there are
+// several for a promotion, or picking the piece on it to move next
+func (t *TUI) pick(sq game.Square) []game.Move {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var moves []game.Move
+	if t.selected != nil {
+		for _, m := range t.legal {
+			if m.From == *t.selected && m.To == sq {
+				moves = append(moves, m)
+			}
+		}
+		if len(moves) > 0 {
+			return moves
+		}
+	}
+	t.selected = nil
+	for _, m := range t.legal {
+		if m.From == sq {
+			t.selected = &sq
+			break
+		}
+	}
+	return nil
+}
+
+// Ask which piece a pawn is promoted to
+func (t *TUI) promotion(moves []game.Move) (string, bool) {
+	t.ask("Promote to (q)ueen, (r)ook, (b)ishop or k(n)ight?")
+	defer t.ask("")
+	for {
+		k, err := t.nextKey()
+		if err != nil || k.code == keyEscape {
+			return "", false
+		}
+		for _, m := range moves {
+			if k.code == keyRune && strings.HasSuffix(m.String(), string(k.r)) {
+				return m.String(), true
+			}
+		}
+	}
+}
+
+// AcceptTakeback asks whether to let the opponent take back their last move
+func (t *TUI) AcceptTakeback() bool {
+	return t.confirm("Your opponent asks to take back their last move. Accept? (y/n)")
+}
+
+// Ask a yes or no question
+func (t *TUI) confirm(question string) bool {
+	t.ask(question)
+	defer t.ask("")
+	for {
+		k, err := t.nextKey()
+		if err != nil {
+			return false
+		}
+		switch {
+		case k.code == keyRune && (k.r == 'y' || k.r == 'Y'):
+			return true
+		case k.code == keyRune && (k.r == 'n' || k.r == 'N'), k.code == keyEscape:
+			return false
+		}
+	}
+}
+
+// Read a line typed after the prompt, e.g. a move like "Nf3" or a command like "pgn"
+func (t *TUI) readLine(prompt string) (string, bool) {
+	defer t.ask("")
+	var line []rune
+	for {
+		t.ask(prompt + string(line) + "_")
+		k, err := t.nextKey()
+		if err != nil {
+			return "", false
+		}
+		switch k.code {
+		case keyEnter:
+			return strings.TrimSpace(string(line)), true
+		case keyEscape, keyInterrupt:
+			return "", false
+		case keyBackspace:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case keyRune:
+			line = append(line, k.r)
+		}
+	}
+}
+
+// Show a question or command on the bottom line, or the help if it is empty
+func (t *TUI) ask(prompt string) {
+	t.mu.Lock()
+	t.prompt = prompt
+	t.mu.Unlock()
+	t.draw()
+}
+
+// #######################################################################
+// (Section 3) Renderer ##################################################
+// #######################################################################
+
+func (t *TUI) Board(v game.BoardView) {
+	t.mu.Lock()
+	t.view, t.viewAt = v, time.Now()
+	t.mu.Unlock()
+	t.draw()
+}
+
+// Moves redraws the board, as the moves are always shown beside it
+func (t *TUI) Moves(v game.BoardView) {
+	t.Board(v)
+}
+
+func (t *TUI) Message(text string) {
+	t.mu.Lock()
+	t.messages = append(t.messages, strings.Split(text, "\n")...)
+	if len(t.messages) > maxMessages {
+		t.messages = t.messages[len(t.messages)-maxMessages:]
+	}
+	t.mu.Unlock()
+	t.draw()
+}
+
+func (t *TUI) GameOver(o game.Outcome) {
+	t.mu.Lock()
+	t.over = true
+	t.mu.Unlock()
+	t.Message("Game End: " + o.String())
+}
+
+// Draw the whole screen
+func (t *TUI) draw() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+	// In raw mode a new line does not return to the start of the line
+	fmt.Fprint(t.out, "\x1b[H"+strings.Join(t.frame(time.Now()), "\x1b[K\r\n")+"\x1b[K\x1b[J")
+}
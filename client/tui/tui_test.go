@@ -0,0 +1,145 @@
+package tui
+
+import (
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("\x1b[A\x1b[D\rq\x1b[<0;16;4M\x1b[<0;16;4m\x1b[<2;1;1M\x1b[5~:\x7f\x1b"))
+	want := []key{
+		{code: keyUp},
+		{code: keyLeft},
+		{code: keyEnter},
+		{code: keyRune, r: 'q'},
+		{code: keyMouse, x: 16, y: 4}, // Releases, other buttons, and other keys are left out
+		{code: keyRune, r: ':'},
+		{code: keyBackspace},
+		{code: keyEscape},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+}
+
+// Play a game through the TUI, pressing the keys and returning the game and its outcome
+func play(t *testing.T, keys []key, params game.HotseatParams) (*TUI, *game.GameState, game.Outcome) {
+	ui := newTUI(io.Discard)
+	params.Input, params.Output = ui, ui
+	g, err := game.InitHotseat(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for _, k := range keys {
+			ui.keys <- k
+		}
+	}()
+
+	done := make(chan game.Outcome)
+	go func() { done <- g.PlayHotseat() }()
+	select {
+	case o := <-done:
+		return ui, g, o
+	case <-time.After(5 * time.Second):
+		t.Fatal("the game did not end")
+	}
+	return nil, nil, game.Outcome{}
+}
+
+func TestChooseMoves(t *testing.T) {
+	enter, up := key{code: keyEnter}, key{code: keyUp}
+	// Click at the middle of a square in the column and row of the board as drawn
+	click := func(col int, row int) key {
+		return key{code: keyMouse, x: boardLeft + col*3 + 1, y: boardTop + row}
+	}
+	keys := []key{
+		enter, up, up, enter, // The cursor starts on e2, so this is e2e4
+		click(4, 1), click(4, 3), // e7e5
+		click(6, 7), enter, // Picking the knight on g1 twice keeps it picked
+		click(1, 7), click(2, 5), // Nc3
+		{code: keyRune, r: ':'}, {code: keyRune, r: 'N'}, {code: keyRune, r: 'c'}, {code: keyRune, r: '6'}, enter,
+		{code: keyRune, r: 'u'},
+		{code: keyRune, r: 'q'}, {code: keyRune, r: 'n'}, // Not resigning after all
+		{code: keyInterrupt}, {code: keyRune, r: 'y'},
+	}
+	ui, g, o := play(t, keys, game.HotseatParams{})
+	if o != (game.Outcome{Result: game.WhiteWins, Reason: game.Resignation}) {
+		t.Error("expected Black to resign, got ", o)
+	}
+	if moves := strings.Join(g.SANMoves(), " "); moves != "e4 e5 Nc3" {
+		t.Error("got moves ", moves)
+	}
+	if !ui.over || ui.messages[len(ui.messages)-1] != "Game End: "+o.String() {
+		t.Error("expected the end of the game to be shown, got ", ui.messages)
+	}
+}
+
+func TestPromotion(t *testing.T) {
+	// The cursor starts on e2, where b7 is drawn at column 1 and row 1
+	keys := []key{
+		{code: keyLeft}, {code: keyLeft}, {code: keyLeft}, {code: keyUp}, {code: keyUp}, {code: keyUp}, {code: keyUp}, {code: keyUp},
+		{code: keyEnter}, {code: keyUp}, {code: keyEnter}, {code: keyRune, r: 'n'},
+		{code: keyRune, r: 'q'}, {code: keyRune, r: 'y'},
+	}
+	_, g, _ := play(t, keys, game.HotseatParams{FEN: "k7/1P6/8/8/8/8/8/4K3 w - - 0 1"})
+	if moves := strings.Join(g.SANMoves(), " "); moves != "b8=N" {
+		t.Error("got moves ", moves)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	p, err := game.ParseFEN("rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui := newTUI(io.Discard)
+	ui.SetPeer("bob")
+	ui.Message("Your Turn")
+	// Black's view of the board puts h1 at the top left
+	ui.Board(game.BoardView{Position: *p, White: false, Timed: true, Clocks: [2]time.Duration{time.Minute, time.Minute}})
+
+	lines := ui.frame(ui.viewAt)
+	for i, want := range map[int]string{
+		0:  "Waiting for bob's move",
+		1:  "   h  g  f  e  d  c  b  a",
+		2:  "1 ",
+		16: "Your Turn",
+	} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("line %d: expected %q, got %q", i, want, lines[i])
+		}
+	}
+	frame := strings.Join(lines, "\n")
+	for _, want := range []string{"White 1:00   Black 1:00", "Opponent  bob", "No moves yet", help} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("expected %q in the frame:\n%s", want, frame)
+		}
+	}
+
+	// The panes line up beside the board
+	for _, line := range lines[1:15] {
+		text := []rune(regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(line, ""))
+		if len(text) > 32 && (string(text[28:32]) != "    " || text[32] == ' ') {
+			t.Errorf("expected the pane at column 33 in %q", string(text))
+		}
+	}
+}
+
+func TestCaptured(t *testing.T) {
+	// White took a queen and a pawn, and Black a knight, while a White pawn was promoted
+	p, err := game.ParseFEN("1nb1kbnr/rppppppp/8/8/8/8/PPPPPP2/RQBQKBNR w KQk - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	white, black := captured(p)
+	if white != "♛♟" || black != "♘♙" {
+		t.Errorf("got %q and %q", white, black)
+	}
+}
@@ -0,0 +1,261 @@
+package uci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// #######################################################################
+// (Section 1) External Engines ##########################################
+// #######################################################################
+
+var ErrorEngineExited = errors.New("engine exited")
+var ErrorEngineTimeout = errors.New("engine did not answer in time")
+
+// How long an external engine has to answer, as variables so tests can shorten them
+var (
+	answerTime = 10 * time.Second // For a command, beyond any time it was given to think
+	depthTime  = 5 * time.Minute  // For a search limited only by depth
+	stopTime   = time.Second      // For its best move once told to stop
+)
+
+// Client drives an external UCI engine running as a child process
+// It implements game.Analyser, so the engine can play, give hints, or analyse games
+type Client struct {
+	Name     string // From the engine's "id name", or its path
+	cmd      *exec.Cmd
+	in       io.WriteCloser
+	lines    chan string // Lines the engine writes, closed when it exits
+	chess960 bool        // The engine's UCI_Chess960 option
+}
+
+// Start runs the engine at the given path and waits until it is ready
+func Start(path string, args ...string) (*Client, error) {
+
+	cmd := exec.Command(path, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("cannot start engine %s: %w", path, err)
+	}
+
+	c := &Client{Name: path, cmd: cmd, in: in, lines: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+		close(c.lines)
+	}()
+
+	if err = c.send("uci"); err != nil {
+		c.Close()
+		return nil, err
+	}
+	err = c.readUntil("uciok", answerTime, func(line string) {
+		if name := strings.TrimPrefix(line, "id name "); name != line {
+			c.Name = name
+		}
+	})
+	if err == nil {
+		err = c.ready()
+	}
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("engine %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// NewGame tells the engine the next position is from a different game
+func (c *Client) NewGame() error {
+	if err := c.send("ucinewgame"); err != nil {
+		return err
+	}
+	return c.ready()
+}
+
+// Analyse asks the engine for the best move in the position reached by playing the moves from the FEN
+func (c *Client) Analyse(fen string, moves []string, depth int, moveTime time.Duration) (game.Analysis, error) {
+
+	var a game.Analysis
+	position := "position fen " + fen
+	if fen == game.StartFEN {
+		position = "position startpos"
+	}
+	if len(moves) > 0 {
+		position += " moves " + strings.Join(moves, " ")
+	}
+
+	var limits []string
+	if depth > 0 {
+		limits = append(limits, "depth", strconv.Itoa(depth))
+	}
+	if moveTime > 0 {
+		limits = append(limits, "movetime", strconv.FormatInt(moveTime.Milliseconds(), 10))
+	}
+	if len(limits) == 0 {
+		return a, fmt.Errorf("the engine needs a depth or a time per move")
+	}
+
+	// Chess960 castling moves are written as the king taking its rook, which engines
+	// only expect once told the game is Chess960
+	if p, err := game.ParseFEN(fen); err == nil && p.Chess960() != c.chess960 {
+		if err = c.send(fmt.Sprintf("setoption name UCI_Chess960 value %t", p.Chess960())); err != nil {
+			return a, err
+		}
+		c.chess960 = p.Chess960()
+	}
+	if err := c.send(position); err != nil {
+		return a, err
+	}
+	if err := c.send("go " + strings.Join(limits, " ")); err != nil {
+		return a, err
+	}
+
+	// A search limited only by depth may take a long time, but not forever
+	wait := depthTime
+	if moveTime > 0 {
+		wait = moveTime + answerTime
+	}
+	var bestmove string
+	err := c.readUntil("bestmove", wait, func(line string) {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 1 && fields[0] == "bestmove":
+			bestmove = fields[1]
+		case len(fields) > 0 && fields[0] == "info":
+			parseInfo(fields[1:], &a)
+		}
+	})
+	if errors.Is(err, ErrorEngineTimeout) {
+		c.stop()
+	}
+	if err != nil {
+		return a, err
+	}
+	if bestmove == "" || bestmove == "0000" || bestmove == "(none)" {
+		return a, game.ErrorNoMoves
+	}
+	a.Move = bestmove
+	if len(a.PV) == 0 || a.PV[0] != bestmove {
+		// The last line reported was for a different move, so it does not describe this one
+		a.PV = []string{bestmove}
+	}
+	return a, nil
+}
+
+// Read the score and line from an info line's fields, leaving the others as they are
+// e.g. "depth 5 score cp 31 nodes 1200 pv e2e4 e7e5"
+func parseInfo(fields []string, a *game.Analysis) {
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "score":
+			if i+2 >= len(fields) {
+				return
+			}
+			n, err := strconv.Atoi(fields[i+2])
+			if err != nil {
+				return
+			}
+			switch fields[i+1] {
+			case "cp":
+				a.Score, a.Mate = n, 0
+			case "mate":
+				a.Mate = n
+			}
+			i += 2
+		case "pv":
+			a.PV = append([]string(nil), fields[i+1:]...)
+			return
+		case "string":
+			// The rest of the line is free text
+			return
+		}
+	}
+}
+
+// Close asks the engine to quit, and kills it if it does not
+func (c *Client) Close() error {
+	c.send("quit")
+	c.in.Close()
+	// Anything the engine still writes is thrown away
+	go func() {
+		for range c.lines {
+		}
+	}()
+
+	exited := make(chan error, 1)
+	go func() { exited <- c.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(time.Second):
+		c.cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+// Stop a search that ran out of time and throw away its best move, so the move is not
+// taken as the answer to the next search, or kill the engine if it does not stop
+func (c *Client) stop() {
+	err := c.send("stop")
+	if err == nil {
+		err = c.readUntil("bestmove", stopTime, nil)
+	}
+	if err != nil {
+		c.cmd.Process.Kill()
+	}
+}
+
+// Write a command to the engine
+func (c *Client) send(command string) error {
+	if _, err := fmt.Fprintln(c.in, command); err != nil {
+		return fmt.Errorf("cannot send %q to engine: %w", command, err)
+	}
+	return nil
+}
+
+func (c *Client) ready() error {
+	if err := c.send("isready"); err != nil {
+		return err
+	}
+	return c.readUntil("readyok", answerTime, nil)
+}
+
+// Pass the engine's lines to handle until one starts with the given word,
+// waiting no longer than the given time
+func (c *Client) readUntil(word string, wait time.Duration, handle func(line string)) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return ErrorEngineExited
+			}
+			if handle != nil {
+				handle(line)
+			}
+			if fields := strings.Fields(line); len(fields) > 0 && fields[0] == word {
+				return nil
+			}
+		case <-timer.C:
+			return ErrorEngineTimeout
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package uci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// The test binary runs itself as a fake engine when this is set to how the engine should behave
+const fakeEngineEnv = "CHESS_FAKE_UCI_ENGINE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeEngineEnv); mode != "" {
+		fakeEngine(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// A fake UCI engine that plays the first legal move, or misbehaves as the mode asks:
+// "illegal" plays a move that is never legal, "crash" exits instead of moving,
+// "slow" only moves once told to stop, and "hung" never moves at all
+func fakeEngine(mode string) {
+	var pos *game.Position
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			pos, _ = game.ParseFEN(game.StartFEN)
+			i := 2
+			if fields[1] == "fen" {
+				pos, _ = game.ParseFEN(strings.Join(fields[2:8], " "))
+				i = 8
+			}
+			if i < len(fields) && fields[i] == "moves" {
+				for _, s := range fields[i+1:] {
+					if m, ok := findMove(pos, s); ok {
+						pos.Play(m)
+					}
+				}
+			}
+		case "go":
+			switch mode {
+			case "illegal":
+				fmt.Println("bestmove a1a1")
+				continue
+			case "crash":
+				os.Exit(1)
+			case "slow", "hung":
+				continue
+			}
+			moves := game.LegalMoves(pos)
+			if len(moves) == 0 {
+				fmt.Println("bestmove 0000")
+				continue
+			}
+			fmt.Printf("info depth 1 score cp 25 pv %s\n", moves[0])
+			fmt.Printf("info depth 2 score mate -3 nodes 10 pv %s\n", moves[0])
+			fmt.Printf("bestmove %s\n", moves[0])
+		case "stop":
+			if mode == "slow" {
+				fmt.Printf("bestmove %s\n", game.LegalMoves(pos)[0])
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+// Start the fake engine in the given mode
+func startFake(t *testing.T, mode string) *Client {
+	t.Setenv(fakeEngineEnv, mode)
+	c, err := Start(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient(t *testing.T) {
+	c := startFake(t, "normal")
+	if c.Name != "Fake Engine" {
+		t.Error("got name ", c.Name)
+	}
+	if err := c.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := c.Analyse(game.StartFEN, []string{"e2e4", "e7e5"}, 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := game.ParseFEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	if want := game.LegalMoves(p)[0].String(); a.Move != want {
+		t.Errorf("expected %s, got %s", want, a.Move)
+	}
+	if a.Score != 25 || a.Mate != -3 || len(a.PV) != 1 {
+		t.Errorf("got analysis %+v", a)
+	}
+
+	// Checkmated, so there is no move
+	_, err = c.Analyse(game.StartFEN, []string{"f2f3", "e7e5", "g2g4", "d8h4"}, 1, 0)
+	if !errors.Is(err, game.ErrorNoMoves) {
+		t.Error("expected no moves, got ", err)
+	}
+}
+
+func TestClientFailures(t *testing.T) {
+
+	// An illegal move is passed on for the game to reject
+	c := startFake(t, "illegal")
+	a, err := c.Analyse(game.StartFEN, nil, 1, 0)
+	if err != nil || a.Move != "a1a1" {
+		t.Error("expected a1a1, got ", a.Move, err)
+	}
+
+	c = startFake(t, "crash")
+	if _, err = c.Analyse(game.StartFEN, nil, 1, 0); !errors.Is(err, ErrorEngineExited) {
+		t.Error("expected the engine to exit, got ", err)
+	}
+
+	if _, err = Start(os.Args[0] + "-missing"); err == nil {
+		t.Error("expected a missing engine to fail to start")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	answerTime, depthTime, stopTime = 50*time.Millisecond, 50*time.Millisecond, 50*time.Millisecond
+	defer func() { answerTime, depthTime, stopTime = 10*time.Second, 5*time.Minute, time.Second }()
+
+	// The move an engine gives once stopped is not left for the next search to read
+	c := startFake(t, "slow")
+	if _, err := c.Analyse(game.StartFEN, nil, 0, time.Millisecond); !errors.Is(err, ErrorEngineTimeout) {
+		t.Error("expected a timeout, got ", err)
+	}
+	if err := c.readUntil("bestmove", 50*time.Millisecond, nil); !errors.Is(err, ErrorEngineTimeout) {
+		t.Error("expected no move left to read, got ", err)
+	}
+
+	// An engine that does not stop is killed, even in a search limited only by depth
+	c = startFake(t, "hung")
+	if _, err := c.Analyse(game.StartFEN, nil, 20, 0); !errors.Is(err, ErrorEngineTimeout) {
+		t.Error("expected a timeout, got ", err)
+	}
+	if err := c.ready(); err == nil {
+		t.Error("expected the engine to be killed")
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	var a game.Analysis
+	parseInfo(strings.Fields("depth 3 seldepth 5 score cp -40 upperbound nodes 99 pv g1f3 d7d5"), &a)
+	if a.Score != -40 || strings.Join(a.PV, " ") != "g1f3 d7d5" {
+		t.Errorf("got %+v", a)
+	}
+	parseInfo(strings.Fields("string score cp 100"), &a)
+	if a.Score != -40 {
+		t.Error("expected an info string to be ignored, got ", a.Score)
+	}
+}
@@ -0,0 +1,341 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// #######################################################################
+// (Section 1) UCI Loop ##################################################
+// #######################################################################
+
+const defaultHash = 16 // Megabytes
+
+// Engine speaks the Universal Chess Interface for the game package's engine
+type Engine struct {
+	out      io.Writer
+	mu       sync.Mutex // Guards out, which the search writes to while commands are read
+	engine   *game.Engine
+	pos      *game.Position
+	chess960 bool           // UCI_Chess960, so castling is the king taking its rook even from standard squares
+	seen     map[uint64]int // Positions before pos in the game, by hash
+	halt     func()         // Stops the running search, and can be called more than once, or nil
+	held     chan struct{}  // Closed to let an infinite or pondering search send its bestmove
+	done     chan struct{}  // Closed once the running search has sent its bestmove
+
+	ponder     bool          // The running search is pondering on the opponent's move
+	ponderTime time.Duration // Time to search for once the move pondered on is played
+}
+
+func NewEngine(out io.Writer) *Engine {
+	e := &Engine{out: out, engine: game.NewEngine(defaultHash)}
+	e.pos, _ = game.ParseFEN(game.StartFEN)
+	e.seen = map[uint64]int{}
+	return e
+}
+
+// Run reads commands until "quit" or the end of the input
+func (e *Engine) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" {
+			break
+		}
+		if err := e.command(fields[0], fields[1:]); err != nil {
+			// GUIs ignore lines they do not understand, so errors are sent as info strings
+			e.send("info string %v", err)
+		}
+	}
+	e.stopSearch()
+	return scanner.Err()
+}
+
+func (e *Engine) command(name string, args []string) error {
+	switch name {
+	case "uci":
+		e.send("id name jkunzler0/chess")
+		e.send("id author jkunzler0")
+		e.send("option name Hash type spin default %d min 1 max 1024", defaultHash)
+		e.send("option name UCI_Chess960 type check default false")
+		e.send("uciok")
+	case "isready":
+		e.send("readyok")
+	case "setoption":
+		return e.setOption(args)
+	case "ucinewgame":
+		e.stopSearch()
+		e.engine.Clear()
+	case "position":
+		e.stopSearch()
+		return e.position(args)
+	case "go":
+		e.stopSearch()
+		return e.goSearch(args)
+	case "stop":
+		e.stopSearch()
+	case "ponderhit":
+		e.ponderHit()
+	case "debug", "register":
+		// Not supported, and safe to ignore
+	default:
+		return fmt.Errorf("unknown command %s", name)
+	}
+	return nil
+}
+
+// Write a line of output
+func (e *Engine) send(format string, a ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.out, format+"\n", a...)
+}
+
+// e.g. "setoption name Hash value 64"
+func (e *Engine) setOption(args []string) error {
+	if len(args) != 4 || args[0] != "name" || args[2] != "value" {
+		return fmt.Errorf("cannot read setoption %s", strings.Join(args, " "))
+	}
+	switch args[1] {
+	case "Hash":
+		mb, err := strconv.Atoi(args[3])
+		if err != nil || mb < 1 {
+			return fmt.Errorf("invalid Hash %s", args[3])
+		}
+		e.stopSearch()
+		e.engine = game.NewEngine(mb)
+	case "UCI_Chess960":
+		on, err := strconv.ParseBool(args[3])
+		if err != nil {
+			return fmt.Errorf("invalid UCI_Chess960 %s", args[3])
+		}
+		e.chess960 = on
+	default:
+		return fmt.Errorf("unknown option %s", args[1])
+	}
+	return nil
+}
+
+// #######################################################################
+// (Section 2) Position ##################################################
+// #######################################################################
+
+// e.g. "position startpos moves e2e4 e7e5" or "position fen <FEN> moves e2e4"
+func (e *Engine) position(args []string) error {
+
+	var fen string
+	switch {
+	case len(args) > 0 && args[0] == "startpos":
+		fen, args = game.StartFEN, args[1:]
+	case len(args) > 0 && args[0] == "fen":
+		end := 1
+		for end < len(args) && args[end] != "moves" {
+			end++
+		}
+		fen, args = strings.Join(args[1:end], " "), args[end:]
+	default:
+		return fmt.Errorf("position needs startpos or fen")
+	}
+
+	pos, err := game.ParseFEN(fen)
+	if err != nil {
+		return err
+	}
+	// Without the option, Chess960 castling is still recognised from a FEN whose
+	// kings or rooks are not on their standard squares
+	if e.chess960 {
+		pos.SetChess960()
+	}
+	seen := map[uint64]int{}
+
+	if len(args) > 0 {
+		if args[0] != "moves" {
+			return fmt.Errorf("expected moves, got %s", args[0])
+		}
+		for _, s := range args[1:] {
+			m, ok := findMove(pos, s)
+			if !ok {
+				return fmt.Errorf("illegal move %s in position %s", s, pos.FEN())
+			}
+			seen[pos.Hash()]++
+			pos.Play(m)
+		}
+	}
+
+	e.pos, e.seen = pos, seen
+	return nil
+}
+
+// Return the legal move written in the coordinate notation UCI uses, e.g. "e7e8q"
+func findMove(p *game.Position, s string) (game.Move, bool) {
+	for _, m := range game.LegalMoves(p) {
+		if m.String() == s {
+			return m, true
+		}
+	}
+	return game.Move{}, false
+}
+
+// #######################################################################
+// (Section 3) Search ####################################################
+// #######################################################################
+
+// e.g. "go depth 6", "go movetime 1000", "go wtime 60000 btime 60000 winc 1000 binc 1000", or "go infinite"
+// "go nodes" stops the search after about that many nodes, and "go mate N" searches deep enough
+// for a mate in N moves. An infinite or pondering search holds its bestmove until "stop",
+// or for pondering "ponderhit", even if it finishes first
+func (e *Engine) goSearch(args []string) error {
+
+	var params game.SearchParams
+	var clock [2]time.Duration // Time left, then increment, for the side to move
+	var movesToGo int
+	var infinite, ponder bool
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" || args[i] == "ponder" {
+			infinite = infinite || args[i] == "infinite"
+			ponder = ponder || args[i] == "ponder"
+			continue
+		}
+		if i+1 == len(args) {
+			return fmt.Errorf("go %s needs a value", args[i])
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			return fmt.Errorf("invalid go %s %s", args[i], args[i+1])
+		}
+		ms := time.Duration(n) * time.Millisecond
+		white := e.pos.WhiteTurn()
+		switch args[i] {
+		case "depth":
+			params.Depth = n
+		case "movetime":
+			params.MoveTime = ms
+		case "wtime", "btime":
+			if (args[i] == "wtime") == white {
+				clock[0] = ms
+			}
+		case "winc", "binc":
+			if (args[i] == "winc") == white {
+				clock[1] = ms
+			}
+		case "movestogo":
+			movesToGo = n
+		case "nodes":
+			params.Nodes = n
+		case "mate":
+			// A mate in n moves is found by searching the n moves and the replies between them
+			params.Depth = 2*n - 1
+		default:
+			return fmt.Errorf("unknown go %s", args[i])
+		}
+		i++
+	}
+	if clock[0] > 0 && params.MoveTime == 0 {
+		params.MoveTime = moveTime(clock[0], clock[1], movesToGo)
+	}
+	// Pondering runs on the opponent's time, so the clock only starts at ponderhit
+	e.ponder, e.ponderTime = ponder, 0
+	if ponder {
+		e.ponderTime, params.MoveTime = params.MoveTime, 0
+	}
+
+	stop, held, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	e.halt = func() { once.Do(func() { close(stop) }) }
+	if !infinite && !ponder {
+		close(held)
+	}
+	params.Stop, params.Seen = stop, e.seen
+	e.held, e.done = held, done
+
+	pos := *e.pos
+	e.engine.Info = e.sendInfo
+	go func() {
+		defer close(done)
+		m, _, ok := e.engine.Search(&pos, params)
+		<-held
+		if !ok {
+			// There is no move in checkmate or stalemate
+			e.send("bestmove 0000")
+			return
+		}
+		e.send("bestmove %s", m)
+	}()
+	return nil
+}
+
+// Share out the time left over the moves still to play, keeping most of the increment in hand
+func moveTime(left time.Duration, increment time.Duration, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+	t := left/time.Duration(movesToGo) + increment*3/4
+	if t > left/2 {
+		t = left / 2
+	}
+	if t < time.Millisecond {
+		t = time.Millisecond
+	}
+	return t
+}
+
+// Stop the running search and wait for its bestmove
+func (e *Engine) stopSearch() {
+	if e.done == nil {
+		return
+	}
+	e.halt()
+	e.release()
+	<-e.done
+	e.halt, e.held, e.done = nil, nil, nil
+	e.ponder = false
+}
+
+// The move pondered on was played, so the search goes on as a normal one
+// and sends its bestmove once it finishes
+func (e *Engine) ponderHit() {
+	if e.done == nil || !e.ponder {
+		return
+	}
+	e.ponder = false
+	e.release()
+	if e.ponderTime > 0 {
+		time.AfterFunc(e.ponderTime, e.halt)
+	}
+}
+
+// Let a held search send its bestmove
+func (e *Engine) release() {
+	select {
+	case <-e.held:
+	default:
+		close(e.held)
+	}
+}
+
+func (e *Engine) sendInfo(info game.SearchInfo) {
+	score := fmt.Sprintf("cp %d", info.Score)
+	if info.Mate != 0 {
+		score = fmt.Sprintf("mate %d", info.Mate)
+	}
+	var pv []string
+	for _, m := range info.PV {
+		pv = append(pv, m.String())
+	}
+	nps := int64(0)
+	if info.Time > 0 {
+		nps = int64(float64(info.Nodes) / info.Time.Seconds())
+	}
+	e.send("info depth %d score %s nodes %d nps %d time %d pv %s",
+		info.Depth, score, info.Nodes, nps, info.Time.Milliseconds(), strings.Join(pv, " "))
+}
@@ -0,0 +1,198 @@
+package uci
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run the commands and return the lines of output
+func run(t *testing.T, commands string) []string {
+	var out bytes.Buffer
+	if err := NewEngine(&out).Run(strings.NewReader(commands)); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+// Return the move of the last bestmove line
+func bestMove(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "bestmove ") {
+			return strings.TrimPrefix(lines[i], "bestmove ")
+		}
+	}
+	return ""
+}
+
+func TestHandshake(t *testing.T) {
+	lines := run(t, "uci\nisready\nquit\n")
+	if lines[0] != "id name jkunzler0/chess" {
+		t.Error("got first line ", lines[0])
+	}
+	if n := len(lines); n < 2 || lines[n-2] != "uciok" || lines[n-1] != "readyok" {
+		t.Error("expected uciok then readyok, got ", lines)
+	}
+}
+
+func TestPosition(t *testing.T) {
+
+	// Fool's mate from the start position
+	lines := run(t, "position startpos moves f2f3 e7e5 g2g4\ngo depth 2\nquit\n")
+	if m := bestMove(lines); m != "d8h4" {
+		t.Error("expected d8h4, got ", m, lines)
+	}
+
+	// Back rank mate from a FEN
+	lines = run(t, "position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1\ngo depth 3\nquit\n")
+	if m := bestMove(lines); m != "a1a8" {
+		t.Error("expected a1a8, got ", m, lines)
+	}
+	found := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "info depth") && strings.Contains(line, "score mate 1") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected a mate score, got ", lines)
+	}
+
+	// Promotion moves are written with the piece
+	lines = run(t, "position fen 8/4P3/8/8/8/8/k7/7K w - - 0 1 moves e7e8q a2b2\ngo depth 1\nquit\n")
+	if m := bestMove(lines); m == "" {
+		t.Error("expected a move after e7e8q, got ", lines)
+	}
+
+	// Chess960 castling is written as the king taking its rook
+	lines = run(t, "position fen 1r2k1r1/1p4p1/8/8/8/8/8/RK4R1 w KQk - 0 1 moves b1a1\nisready\nquit\n")
+	if lines[0] != "readyok" {
+		t.Error("expected b1a1 to castle, got ", lines)
+	}
+
+	// With UCI_Chess960 a start position that looks standard still castles by taking the rook
+	lines = run(t, "setoption name UCI_Chess960 value true\nposition startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 e1h1\nisready\nquit\n")
+	if lines[0] != "readyok" {
+		t.Error("expected e1h1 to castle, got ", lines)
+	}
+	lines = run(t, "position startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 e1h1\nisready\nquit\n")
+	if !strings.HasPrefix(lines[0], "info string") {
+		t.Error("expected e1h1 to be illegal without UCI_Chess960, got ", lines)
+	}
+
+	// Checkmated, so there is no move
+	lines = run(t, "position startpos moves f2f3 e7e5 g2g4 d8h4\ngo depth 2\nquit\n")
+	if m := bestMove(lines); m != "0000" {
+		t.Error("expected 0000, got ", m)
+	}
+}
+
+func TestBadCommands(t *testing.T) {
+	lines := run(t, "position startpos moves e2e5\nfoo\ngo depth x\nisready\nquit\n")
+	for _, line := range lines[:3] {
+		if !strings.HasPrefix(line, "info string") {
+			t.Error("expected an info string, got ", line)
+		}
+	}
+	if lines[3] != "readyok" {
+		t.Error("expected readyok, got ", lines[3])
+	}
+}
+
+func TestStop(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEngine(&out)
+	if err := e.command("go", []string{"infinite"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	e.command("stop", nil)
+	if d := time.Since(start); d > time.Second {
+		t.Error("stop took ", d)
+	}
+	if m := bestMove(strings.Split(strings.TrimSpace(out.String()), "\n")); len(m) < 4 {
+		t.Error("expected a bestmove after stop, got ", out.String())
+	}
+}
+
+func TestHeldBestMove(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEngine(&out)
+	written := func() []string {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return strings.Split(strings.TrimSpace(out.String()), "\n")
+	}
+
+	// The mate in one is found at once, but an infinite search waits for stop
+	e.command("position", strings.Fields("fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"))
+	e.command("go", []string{"infinite"})
+	time.Sleep(50 * time.Millisecond)
+	if m := bestMove(written()); m != "" {
+		t.Error("expected no bestmove before stop, got ", m)
+	}
+	e.command("stop", nil)
+	if m := bestMove(written()); m != "a1a8" {
+		t.Error("expected a1a8 after stop, got ", m)
+	}
+
+	// A pondering search waits for ponderhit, then searches for its time
+	out.Reset()
+	e.command("position", []string{"startpos"})
+	e.command("go", strings.Fields("ponder movetime 50"))
+	time.Sleep(100 * time.Millisecond)
+	if m := bestMove(written()); m != "" {
+		t.Error("expected no bestmove before ponderhit, got ", m)
+	}
+	e.command("ponderhit", nil)
+	select {
+	case <-e.done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a bestmove after ponderhit")
+	}
+	if m := bestMove(written()); len(m) < 4 {
+		t.Error("expected a bestmove after ponderhit, got ", written())
+	}
+	e.command("stop", nil)
+}
+
+func TestGoLimits(t *testing.T) {
+
+	// Go command, then the position it is given
+	searches := map[string]string{
+		"go nodes 3000": "startpos",
+		"go mate 1":     "fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+	}
+	for x, position := range searches {
+		var out bytes.Buffer
+		e := NewEngine(&out)
+		e.command("position", strings.Fields(position))
+		e.command("go", strings.Fields(x)[1:])
+		// The search finishes by itself, without being stopped
+		select {
+		case <-e.done:
+		case <-time.After(2 * time.Second):
+			t.Fatal(x, " expected the search to finish")
+		}
+		if m := bestMove(strings.Split(strings.TrimSpace(out.String()), "\n")); len(m) < 4 {
+			t.Error(x, " expected a bestmove, got ", out.String())
+		}
+		e.command("stop", nil)
+	}
+}
+
+func TestMoveTime(t *testing.T) {
+	if d := moveTime(60*time.Second, 0, 0); d != 2*time.Second {
+		t.Error("got ", d)
+	}
+	if d := moveTime(60*time.Second, 2*time.Second, 10); d != 7500*time.Millisecond {
+		t.Error("got ", d)
+	}
+	// Never more than half the time left
+	if d := moveTime(time.Second, 10*time.Second, 0); d != 500*time.Millisecond {
+		t.Error("got ", d)
+	}
+}
@@ -0,0 +1,291 @@
+package web
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// #######################################################################
+// (Section 1) Server ####################################################
+// #######################################################################
+
+//go:embed static
+var static embed.FS
+
+var ErrorClosed = errors.New("the web server is closed")
+
+const maxMessages = 50 // Messages kept for pages that connect later
+
+// Server shows the game in a browser, where pieces are moved with the mouse
+// It is both the MoveSource and the Renderer of a game, and the game's rules stay in Go:
+// the page only sends the moves it is given, which the game validates
+type Server struct {
+	URL      string // Where the page is served, e.g. "http://127.0.0.1:8000"
+	server   *http.Server
+	upgrader websocket.Upgrader
+	done     chan struct{} // Closed when the server is closed
+	close    sync.Once
+
+	mu       sync.Mutex // Guards the connections, which only one goroutine may write to, and everything below
+	conns    map[*websocket.Conn]bool
+	moves    chan string   // Takes the first move or command from the pages while one is wanted, nil otherwise
+	answers  chan bool     // Takes the first answer from the pages while a takeback is asked about, nil otherwise
+	pos      game.Position // The position the state was drawn from
+	state    state
+	messages []string
+}
+
+// Start serves the page at addr until Close is called
+// Without a host, e.g. ":8000", only this computer can connect, and other computers
+// only can when a host is given, e.g. "0.0.0.0:8000" for every interface
+func Start(addr string) (*Server, error) {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := newServer()
+	s.URL = "http://" + listener.Addr().String()
+	s.server = &http.Server{Handler: s.handler()}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+func newServer() *Server {
+	return &Server{
+		done:  make(chan struct{}),
+		conns: map[*websocket.Conn]bool{},
+	}
+}
+
+func (s *Server) handler() http.Handler {
+	files, _ := fs.Sub(static, "static")
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/ws", s.serveSocket)
+	return mux
+}
+
+// Close stops serving the page, after telling the pages
+func (s *Server) Close() {
+	s.close.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for conn := range s.conns {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "game closed"))
+			conn.Close()
+		}
+		if s.server != nil {
+			s.server.Close()
+		}
+	})
+}
+
+// SetPeer shows the name of the opponent in a P2P game
+func (s *Server) SetPeer(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Peer = name
+	s.broadcast(update{Type: "state", State: &s.state})
+}
+
+// #######################################################################
+// (Section 2) WebSocket #################################################
+// #######################################################################
+
+// update is sent to the pages
+type update struct {
+	Type  string `json:"type"` // "state", "message", or "takeback" to ask whether to accept one
+	State *state `json:"state,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// state is everything the page draws
+type state struct {
+	FEN      string   `json:"fen"`
+	White    bool     `json:"white"`    // The local player's color, which the board is drawn for
+	LastMove []string `json:"lastMove"` // The squares the last move was from and to, e.g. ["e2", "e4"]
+	Check    string   `json:"check"`    // The square of a king in check, or empty
+	Moves    []string `json:"moves"`    // e.g. "1. e4 e5"
+	Legal    []string `json:"legal"`    // Moves to choose from while a move is wanted, e.g. "e7e8q"
+	Timed    bool     `json:"timed"`
+	Clocks   [2]int64 `json:"clocks"` // Milliseconds left for White, then Black
+	Delay    int64    `json:"delay"`  // Milliseconds of delay left before the running clock counts down
+	Peer     string   `json:"peer"`   // The P2P opponent's name, or empty
+	Over     string   `json:"over"`   // The outcome of the finished game, or empty
+	Wanted   bool     `json:"wanted"` // A move or command is wanted from the page
+}
+
+// request is sent by a page
+type request struct {
+	Type   string `json:"type"`   // "move" for a move or command, or "takeback" to answer one
+	Move   string `json:"move"`   // e.g. "e2e4", "Nf3", or "undo"
+	Accept bool   `json:"accept"` // The answer to a takeback
+}
+
+func (s *Server) serveSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// A page that connects late is brought up to date
+	s.mu.Lock()
+	s.conns[conn] = true
+	for _, m := range s.messages {
+		conn.WriteJSON(update{Type: "message", Text: m})
+	}
+	conn.WriteJSON(update{Type: "state", State: &s.state})
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	for {
+		var req request
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		s.mu.Lock()
+		switch {
+		case req.Type == "move" && req.Move != "" && s.moves != nil:
+			select {
+			case s.moves <- req.Move:
+			default:
+				// Another page already gave the move
+			}
+		case req.Type == "takeback" && s.answers != nil:
+			select {
+			case s.answers <- req.Accept:
+			default:
+			}
+		default:
+			// Nothing is waiting for the page, e.g. on the opponent's turn, so the request is dropped
+			conn.WriteJSON(update{Type: "message", Text: "It is not your turn."})
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Send an update to every page, with s.mu held
+func (s *Server) broadcast(u update) {
+	for conn := range s.conns {
+		if err := conn.WriteJSON(u); err != nil {
+			conn.Close()
+			delete(s.conns, conn)
+		}
+	}
+}
+
+// #######################################################################
+// (Section 3) Moves #####################################################
+// #######################################################################
+
+// NextMove waits for a move or command from a page, until stop is closed
+func (s *Server) NextMove(stop <-chan struct{}) (string, error) {
+	moves := make(chan string, 1)
+	s.mu.Lock()
+	s.moves = moves
+	s.state.Wanted = true
+	for _, m := range game.LegalMoves(&s.pos) {
+		s.state.Legal = append(s.state.Legal, m.String())
+	}
+	s.broadcast(update{Type: "state", State: &s.state})
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.moves = nil
+		s.state.Wanted, s.state.Legal = false, nil
+		s.broadcast(update{Type: "state", State: &s.state})
+		s.mu.Unlock()
+	}()
+
+	select {
+	case move := <-moves:
+		return move, nil
+	case <-s.done:
+		return "", ErrorClosed
+	case <-stop:
+		return "", game.ErrorCanceled
+	}
+}
+
+// AcceptTakeback asks the pages whether to let the opponent take back their last move
+func (s *Server) AcceptTakeback() bool {
+	answers := make(chan bool, 1)
+	s.mu.Lock()
+	s.answers = answers
+	s.broadcast(update{Type: "takeback", Text: "Your opponent asks to take back their last move. Accept?"})
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.answers = nil
+		s.mu.Unlock()
+	}()
+
+	select {
+	case accept := <-answers:
+		return accept
+	case <-s.done:
+		return false
+	}
+}
+
+// #######################################################################
+// (Section 4) Renderer ##################################################
+// #######################################################################
+
+func (s *Server) Board(v game.BoardView) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pos = v.Position
+	s.state.FEN = v.Position.FEN()
+	s.state.White = v.White
+	s.state.LastMove, s.state.Check = nil, ""
+	if v.LastMove != nil {
+		s.state.LastMove = []string{v.LastMove.From.String(), v.LastMove.To.String()}
+	}
+	if v.Check != nil {
+		s.state.Check = v.Check.String()
+	}
+	s.state.Moves = v.Moves
+	s.state.Timed = v.Timed
+	s.state.Clocks = [2]int64{v.Clocks[0].Milliseconds(), v.Clocks[1].Milliseconds()}
+	s.state.Delay = v.Delay.Milliseconds()
+	s.broadcast(update{Type: "state", State: &s.state})
+}
+
+// Moves shows the board, as the moves are always shown beside it
+func (s *Server) Moves(v game.BoardView) {
+	s.Board(v)
+}
+
+func (s *Server) Message(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, text)
+	if len(s.messages) > maxMessages {
+		s.messages = s.messages[len(s.messages)-maxMessages:]
+	}
+	s.broadcast(update{Type: "message", Text: text})
+}
+
+func (s *Server) GameOver(o game.Outcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Over = o.String()
+	s.broadcast(update{Type: "state", State: &s.state})
+}
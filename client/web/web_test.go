@@ -0,0 +1,183 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jkunzler0/chess/client/game"
+)
+
+// Connect a page to the server
+func connect(t *testing.T, s *Server) (*httptest.Server, *websocket.Conn) {
+	ts := httptest.NewServer(s.handler())
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts, conn
+}
+
+// Read updates until one matches
+func waitFor(t *testing.T, conn *websocket.Conn, match func(update) bool) update {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var u update
+		if err := conn.ReadJSON(&u); err != nil {
+			t.Fatal(err)
+		}
+		if match(u) {
+			return u
+		}
+	}
+}
+
+// A move is wanted in the position with the FEN's side to move
+func wanted(side string) func(update) bool {
+	return func(u update) bool {
+		return u.Type == "state" && u.State.Wanted && strings.Contains(u.State.FEN, " "+side+" ")
+	}
+}
+
+func TestPage(t *testing.T) {
+	ts := httptest.NewServer(newServer().handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	page, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), `<svg id="board"`) {
+		t.Error("expected the board page, got ", resp.Status)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	s := newServer()
+	ts, conn := connect(t, s)
+	defer ts.Close()
+	defer s.Close()
+
+	g, err := game.InitHotseat(game.HotseatParams{Input: s, Output: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan game.Outcome, 1)
+	go func() { done <- g.PlayHotseat() }()
+
+	u := waitFor(t, conn, wanted("w"))
+	if len(u.State.Legal) != 20 || u.State.FEN != game.StartFEN || !u.State.White {
+		t.Fatal("expected the start position and its moves, got ", u.State)
+	}
+	conn.WriteJSON(request{Type: "move", Move: "e2e4"})
+	u = waitFor(t, conn, wanted("b"))
+	if strings.Join(u.State.LastMove, "") != "e2e4" || strings.Join(u.State.Moves, "") != "1. e4" {
+		t.Error("expected e4 to be shown, got ", u.State)
+	}
+
+	// The game still checks the moves
+	conn.WriteJSON(request{Type: "move", Move: "e7e4"})
+	waitFor(t, conn, func(u update) bool { return u.Type == "message" && strings.HasPrefix(u.Text, "Error") })
+	conn.WriteJSON(request{Type: "move", Move: "q"})
+
+	u = waitFor(t, conn, func(u update) bool { return u.Type == "state" && u.State.Over != "" })
+	o := <-done
+	if o != (game.Outcome{Result: game.WhiteWins, Reason: game.Resignation}) || u.State.Over != o.String() {
+		t.Error("expected Black to resign, got ", o, u.State.Over)
+	}
+
+	// Once the game is over, no move is wanted
+	conn.WriteJSON(request{Type: "move", Move: "e7e5"})
+	waitFor(t, conn, func(u update) bool { return u.Type == "message" && u.Text == "It is not your turn." })
+
+	// A page that connects late is brought up to date
+	ts2, late := connect(t, s)
+	defer ts2.Close()
+	waitFor(t, late, func(u update) bool { return u.Type == "message" && strings.HasPrefix(u.Text, "Error") })
+	waitFor(t, late, func(u update) bool { return u.Type == "state" && u.State.Over == o.String() })
+}
+
+func TestStart(t *testing.T) {
+
+	// Without a host, only this computer can connect
+	s, err := Start(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !strings.HasPrefix(s.URL, "http://127.0.0.1:") {
+		t.Error("expected to listen on 127.0.0.1, got ", s.URL)
+	}
+}
+
+func TestEarlyMove(t *testing.T) {
+	s := newServer()
+	ts, conn := connect(t, s)
+	defer ts.Close()
+	defer s.Close()
+
+	// A move made before one is wanted is dropped, not played later
+	conn.WriteJSON(request{Type: "move", Move: "e2e4"})
+	waitFor(t, conn, func(u update) bool { return u.Type == "message" && u.Text == "It is not your turn." })
+
+	g, err := game.InitHotseat(game.HotseatParams{Input: s, Output: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan game.Outcome, 1)
+	go func() { done <- g.PlayHotseat() }()
+	waitFor(t, conn, wanted("w"))
+	conn.WriteJSON(request{Type: "move", Move: "d2d4"})
+	waitFor(t, conn, wanted("b"))
+	conn.WriteJSON(request{Type: "move", Move: "q"})
+	<-done
+	if moves := g.SANMoves(); len(moves) != 1 || moves[0] != "d4" {
+		t.Error("expected only d4 to be played, got ", moves)
+	}
+}
+
+func TestTakeback(t *testing.T) {
+	s := newServer()
+	ts, conn := connect(t, s)
+	defer ts.Close()
+	defer s.Close()
+
+	// The peer's side of the P2P channels is played here
+	rch, wch := make(chan string, 1), make(chan string, 1)
+	g, err := game.InitP2P(game.P2PParams{YouStart: false, Input: s, Output: s, ReadChan: rch, WriteChan: wch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPeer("alice")
+	done := make(chan game.Outcome, 1)
+	go func() { done <- g.PlayP2P() }()
+
+	rch <- "e2e4"
+	u := waitFor(t, conn, wanted("b"))
+	if u.State.White || u.State.Peer != "alice" {
+		t.Error("expected the board from Black's side against alice, got ", u.State)
+	}
+	conn.WriteJSON(request{Type: "move", Move: "e5"})
+	if move := <-wch; move != "e5" {
+		t.Error("expected e5 to be sent to the peer, got ", move)
+	}
+
+	rch <- "takeback"
+	waitFor(t, conn, func(u update) bool { return u.Type == "takeback" })
+	conn.WriteJSON(request{Type: "takeback", Accept: true})
+	if answer := <-wch; answer != "accept" {
+		t.Error("expected the takeback to be accepted, got ", answer)
+	}
+	rch <- "q"
+	if o := <-done; o != (game.Outcome{Result: game.BlackWins, Reason: game.Resignation}) {
+		t.Error("expected White to resign, got ", o)
+	}
+	if moves := g.SANMoves(); len(moves) != 0 {
+		t.Error("expected the moves to be taken back, got ", moves)
+	}
+}
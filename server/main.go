@@ -20,6 +20,8 @@ type GameResult struct {
 	WinnerID   string `json:"WinnerID" binding:"required"`
 	LoserID    string `json:"LoserID" binding:"required"`
 	ReporterID string `json:"ReporterID"`
+	Draw       bool   `json:"Draw"`
+	Reason     string `json:"Reason"`
 }
 
 type User struct {
@@ -124,7 +126,7 @@ func gameResultHandler(c *gin.Context) {
 	}
 
 	// validate and proccess the game result
-	verified, err = verify.VerifyMatch(verify.GameResult{WinID: gr.WinnerID, LossID: gr.LoserID, RptID: gr.ReporterID})
+	verified, err = verify.VerifyMatch(verify.GameResult{WinID: gr.WinnerID, LossID: gr.LoserID, RptID: gr.ReporterID, Draw: gr.Draw})
 	if err != nil {
 		c.JSON(400, gin.H{"error": "could not process game result, timeout"})
 		return
@@ -135,6 +137,12 @@ func gameResultHandler(c *gin.Context) {
 		return
 	}
 
+	// Draws are verified, but only wins and losses are scored
+	if gr.Draw {
+		c.JSON(http.StatusOK, gin.H{"message": "game authenticated", "reason": gr.Reason})
+		return
+	}
+
 	err = database.IncrWinLoss(gr.WinnerID, gr.LoserID)
 	if err != nil {
 		c.JSON(400, gin.H{"error": "could not increment win/loss"})
@@ -10,7 +10,7 @@ import (
 func diVerify(gr GameResult) (bool, error) {
 
 	// Step 1: Check if our match is already in the pending game results.
-	if ok, _ := reportMatch(gr.WinID, gr.LossID, gr.RptID); ok {
+	if ok, _ := reportMatch(gr.WinID, gr.LossID, gr.RptID, gr.Draw); ok {
 		return true, nil
 	}
 
@@ -21,7 +21,7 @@ func diVerify(gr GameResult) (bool, error) {
 	defer cancel()
 
 	pendReports.Lock()
-	pendReports.m[gr.RptID] = gameReport{ctx, cancel, gr.WinID, gr.LossID}
+	pendReports.m[gr.RptID] = gameReport{ctx, cancel, gr.WinID, gr.LossID, gr.Draw}
 	pendReports.Unlock()
 
 	// Remove this game result from the pending results before leaving this function
@@ -49,9 +49,9 @@ func removeReport(rptID string) error {
 	return nil
 }
 
-func reportMatch(winID string, lossID string, rptID string) (bool, error) {
+func reportMatch(winID string, lossID string, rptID string, draw bool) (bool, error) {
 	for key, element := range pendReports.m {
-		if key != rptID && element.winID == winID && element.lossID == lossID {
+		if key != rptID && element.winID == winID && element.lossID == lossID && element.draw == draw {
 
 			// Cancel the context of the pending report
 			//		to signal that the match will be verified
@@ -10,6 +10,7 @@ type GameResult struct {
 	WinID  string
 	LossID string
 	RptID  string
+	Draw   bool
 }
 
 type gameReport struct {
@@ -17,6 +18,7 @@ type gameReport struct {
 	ctxCancel context.CancelFunc
 	winID     string
 	lossID    string
+	draw      bool
 }
 
 var pendReports = struct {